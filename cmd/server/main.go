@@ -3,7 +3,9 @@ package main
 import (
 	"context"
 	"fmt"
+	"syscall"
 
+	"github.com/wizact/te-reo-bot/pkg/wotd"
 	"github.com/wizact/te-reo-bot/version"
 
 	"github.com/wizact/yacli"
@@ -21,6 +23,9 @@ func main() {
 
 	app.AddCommand(&StartServerCommand{})
 
+	stop := wotd.InvalidateDictionaryCacheOnSignal(syscall.SIGHUP)
+	defer stop()
+
 	ctx := context.Background()
 
 	app.Run(ctx)
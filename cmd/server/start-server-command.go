@@ -2,11 +2,15 @@ package main
 
 import (
 	"context"
+	"embed"
 	"flag"
 
 	hndl "github.com/wizact/te-reo-bot/pkg/handlers"
 )
 
+//go:embed dictionary.json
+var embeddedDict embed.FS
+
 // StartServerCommand is struct for info required to start an http server
 type StartServerCommand struct {
 	port    string
@@ -56,7 +60,7 @@ func (fc *StartServerCommand) Run(ctx context.Context, args []string) error {
 		fc.address = ""
 	}
 
-	hndl.StartServer(fc.Address(), fc.Port(), fc.Tls())
+	hndl.StartServer(fc.Address(), fc.Port(), fc.Tls(), embeddedDict)
 
 	return nil
 }
@@ -10,7 +10,9 @@ type AppError struct {
 // PostResponse is the tweet/mastodon Id after a successful update operation
 type PostResponse struct {
 	TwitterId string `json:"tweetId"`
+	TweetURL  string `json:"tweet_url,omitempty"`
 	TootId    string `json:"tootId"`
+	TootURL   string `json:"toot_url,omitempty"`
 	Message   string `json:"message"`
 }
 
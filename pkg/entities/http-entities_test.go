@@ -0,0 +1,38 @@
+package entities_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	ent "github.com/wizact/te-reo-bot/pkg/entities"
+)
+
+func TestPostResponseJSON(t *testing.T) {
+	assert := assert.New(t)
+
+	b, e := json.Marshal(&ent.PostResponse{
+		TwitterId: "1",
+		TweetURL:  "https://twitter.com/i/web/status/1",
+		TootId:    "2",
+		TootURL:   "https://mastodon.nz/@tereobot/2",
+	})
+
+	assert.Nil(e)
+	assert.JSONEq(`{
+		"tweetId": "1",
+		"tweet_url": "https://twitter.com/i/web/status/1",
+		"tootId": "2",
+		"toot_url": "https://mastodon.nz/@tereobot/2",
+		"message": ""
+	}`, string(b))
+}
+
+func TestPostResponseJSONOmitsEmptyURLs(t *testing.T) {
+	assert := assert.New(t)
+
+	b, e := json.Marshal(&ent.PostResponse{Message: "No destination has been selected"})
+
+	assert.Nil(e)
+	assert.JSONEq(`{"tweetId": "", "tootId": "", "message": "No destination has been selected"}`, string(b))
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	ent "github.com/wizact/te-reo-bot/pkg/entities"
+)
+
+// BodyLimitMiddleware responds with 413 when the request body is larger than maxBytes, maxBytes <= 0 disables it.
+// The body is read up front so the handlers never see a partial body.
+func BodyLimitMiddleware(maxBytes int64) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		if maxBytes <= 0 {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Body == nil || r.Body == http.NoBody {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			b, err := ioutil.ReadAll(io.LimitReader(r.Body, maxBytes+1))
+			r.Body.Close()
+			if err != nil {
+				http.Error(w, "cannot read the request body", http.StatusBadRequest)
+				return
+			}
+
+			if int64(len(b)) > maxBytes {
+				log.Printf("warn: request body too large, request_path: %s, content_length: %s, max_bytes: %d",
+					r.URL.Path, r.Header.Get("Content-Length"), maxBytes)
+
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+				json.NewEncoder(w).Encode(&ent.FriendlyError{Message: "Request body too large"})
+				return
+			}
+
+			r.Body = ioutil.NopCloser(bytes.NewReader(b))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
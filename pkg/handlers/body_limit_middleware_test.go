@@ -0,0 +1,52 @@
+package handlers_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	hndl "github.com/wizact/te-reo-bot/pkg/handlers"
+)
+
+func TestBodyLimitMiddleware(t *testing.T) {
+	const maxBytes = 1024
+
+	tests := []struct {
+		name     string
+		size     int
+		expected int
+	}{
+		{"at the limit", maxBytes, http.StatusOK},
+		{"one byte over", maxBytes + 1, http.StatusRequestEntityTooLarge},
+		{"twice the limit", maxBytes * 2, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+			logs := captureLog(t)
+
+			var read int
+			h := hndl.BodyLimitMiddleware(maxBytes)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				b, _ := ioutil.ReadAll(r.Body)
+				read = len(b)
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/messages", strings.NewReader(strings.Repeat("a", tt.size))))
+
+			assert.Equal(tt.expected, w.Code)
+			if tt.expected == http.StatusOK {
+				assert.Equal(tt.size, read)
+				assert.Empty(logs.String())
+			} else {
+				assert.JSONEq(`{"message":"Request body too large"}`, w.Body.String())
+				assert.Contains(logs.String(), "warn: request body too large")
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// CompressionMiddleware gzips responses of at least minSizeBytes for clients that accept gzip, 0 compresses every response.
+// Images and binary downloads are sent as they are since they are already compressed.
+func CompressionMiddleware(minSizeBytes int) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+
+			if !acceptsGzip(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			gw := &gzipResponseWriter{ResponseWriter: w, minSizeBytes: minSizeBytes}
+			defer gw.finish()
+
+			next.ServeHTTP(gw, r)
+		})
+	}
+}
+
+func acceptsGzip(r *http.Request) bool {
+	for _, e := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		if strings.TrimSpace(strings.SplitN(e, ";", 2)[0]) == "gzip" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// compressible reports whether a response of the content type is worth compressing
+func compressible(contentType string) bool {
+	return !strings.HasPrefix(contentType, "image/") && !strings.HasPrefix(contentType, "application/octet-stream")
+}
+
+// gzipResponseWriter buffers the response until it knows whether the body reaches minSizeBytes
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	minSizeBytes int
+	status       int
+	buf          bytes.Buffer
+	gz           *gzip.Writer
+	passThrough  bool
+}
+
+func (gw *gzipResponseWriter) WriteHeader(code int) {
+	if gw.status == 0 {
+		gw.status = code
+	}
+}
+
+func (gw *gzipResponseWriter) Write(b []byte) (int, error) {
+	if gw.status == 0 {
+		gw.status = http.StatusOK
+	}
+
+	if gw.gz != nil {
+		return gw.gz.Write(b)
+	}
+	if gw.passThrough {
+		return gw.ResponseWriter.Write(b)
+	}
+
+	gw.buf.Write(b)
+	if gw.buf.Len() < gw.minSizeBytes {
+		return len(b), nil
+	}
+
+	if err := gw.start(); err != nil {
+		return 0, err
+	}
+
+	return len(b), nil
+}
+
+// start sends the headers and the buffered body, compressed unless the response is not worth compressing
+func (gw *gzipResponseWriter) start() error {
+	h := gw.Header()
+	if h.Get("Content-Encoding") != "" || !compressible(h.Get("Content-Type")) {
+		gw.passThrough = true
+		gw.ResponseWriter.WriteHeader(gw.status)
+		_, err := gw.buf.WriteTo(gw.ResponseWriter)
+		return err
+	}
+
+	h.Set("Content-Encoding", "gzip")
+	h.Del("Content-Length")
+	gw.ResponseWriter.WriteHeader(gw.status)
+
+	gw.gz = gzip.NewWriter(gw.ResponseWriter)
+	_, err := gw.buf.WriteTo(gw.gz)
+	return err
+}
+
+// finish flushes a response smaller than minSizeBytes uncompressed, or closes the gzip stream
+func (gw *gzipResponseWriter) finish() {
+	if gw.gz != nil {
+		gw.gz.Close()
+		return
+	}
+	if gw.passThrough {
+		return
+	}
+
+	if gw.status == 0 {
+		gw.status = http.StatusOK
+	}
+	gw.ResponseWriter.WriteHeader(gw.status)
+	gw.buf.WriteTo(gw.ResponseWriter)
+}
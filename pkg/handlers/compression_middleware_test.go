@@ -0,0 +1,109 @@
+package handlers_test
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	hndl "github.com/wizact/te-reo-bot/pkg/handlers"
+)
+
+func bodyHandler(body string) http.Handler {
+	return contentHandler("application/json", body)
+}
+
+func contentHandler(contentType, body string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(body))
+	})
+}
+
+func TestCompressionMiddlewareLargeBody(t *testing.T) {
+	assert := assert.New(t)
+	body := strings.Repeat(`{"word":"kia ora","meaning":"hello"},`, 100)
+
+	h := hndl.CompressionMiddleware(1024)(bodyHandler(body))
+
+	r := httptest.NewRequest(http.MethodGet, "/messages", nil)
+	r.Header.Set("Accept-Encoding", "deflate, gzip;q=1.0")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	assert.Equal(http.StatusCreated, w.Code)
+	assert.Equal("gzip", w.Header().Get("Content-Encoding"))
+	assert.Equal("Accept-Encoding", w.Header().Get("Vary"))
+	assert.Less(w.Body.Len(), len(body))
+
+	gz, err := gzip.NewReader(w.Body)
+	assert.NoError(err)
+	b, err := ioutil.ReadAll(gz)
+	assert.NoError(err)
+	assert.Equal(body, string(b))
+}
+
+func TestCompressionMiddlewareSmallBody(t *testing.T) {
+	assert := assert.New(t)
+	body := `{"message":"kia ora"}`
+
+	h := hndl.CompressionMiddleware(1024)(bodyHandler(body))
+
+	r := httptest.NewRequest(http.MethodGet, "/messages", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	assert.Equal(http.StatusCreated, w.Code)
+	assert.Empty(w.Header().Get("Content-Encoding"))
+	assert.Equal(body, w.Body.String())
+}
+
+func TestCompressionMiddlewareWithoutAcceptEncoding(t *testing.T) {
+	assert := assert.New(t)
+	body := strings.Repeat("a", 4096)
+
+	h := hndl.CompressionMiddleware(1024)(bodyHandler(body))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/messages", nil))
+
+	assert.Empty(w.Header().Get("Content-Encoding"))
+	assert.Equal(body, w.Body.String())
+}
+
+func TestCompressionMiddlewareSkipsBinaryContent(t *testing.T) {
+	for _, ct := range []string{"image/jpeg", "application/octet-stream"} {
+		t.Run(ct, func(t *testing.T) {
+			assert := assert.New(t)
+			body := strings.Repeat("a", 4096)
+
+			h := hndl.CompressionMiddleware(1024)(contentHandler(ct, body))
+
+			r := httptest.NewRequest(http.MethodGet, "/messages?fn=korimako.jpg", nil)
+			r.Header.Set("Accept-Encoding", "gzip")
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			assert.Empty(w.Header().Get("Content-Encoding"))
+			assert.Equal(body, w.Body.String())
+		})
+	}
+}
+
+func TestCompressionMiddlewareZeroMinSizeCompressesEverything(t *testing.T) {
+	assert := assert.New(t)
+
+	h := hndl.CompressionMiddleware(0)(bodyHandler(`{"message":"kia ora"}`))
+
+	r := httptest.NewRequest(http.MethodGet, "/messages", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	assert.Equal("gzip", w.Header().Get("Content-Encoding"))
+}
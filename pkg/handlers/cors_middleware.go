@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// CORSMiddleware allows browsers on the allowed origins to call the api, use "*" to allow any origin.
+// Preflight requests from allowed origins are answered here, so it must wrap the router rather than be added with router.Use.
+func CORSMiddleware(allowedOrigins []string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// the response depends on the origin even when it is not allowed, so shared caches must not mix them up
+			w.Header().Add("Vary", "Origin")
+
+			origin := r.Header.Get("Origin")
+			o, allowed := matchOrigin(allowedOrigins, origin)
+			if origin == "" || !allowed {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("Access-Control-Allow-Origin", o)
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE")
+			w.Header().Set("Access-Control-Allow-Headers", "X-Api-Key, Content-Type")
+
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// matchOrigin returns the value for Access-Control-Allow-Origin when origin is allowed
+func matchOrigin(allowedOrigins []string, origin string) (string, bool) {
+	for _, o := range allowedOrigins {
+		if o == "*" {
+			return "*", true
+		}
+		if o == origin {
+			return origin, true
+		}
+	}
+
+	return "", false
+}
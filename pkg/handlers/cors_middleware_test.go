@@ -0,0 +1,94 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	hndl "github.com/wizact/te-reo-bot/pkg/handlers"
+)
+
+func TestCORSMiddleware(t *testing.T) {
+	tests := []struct {
+		name           string
+		allowedOrigins []string
+		origin         string
+		expectedOrigin string
+	}{
+		{"matching origin", []string{"https://admin.tereobot.nz"}, "https://admin.tereobot.nz", "https://admin.tereobot.nz"},
+		{"non matching origin", []string{"https://admin.tereobot.nz"}, "https://evil.example.com", ""},
+		{"wildcard", []string{"*"}, "https://evil.example.com", "*"},
+		{"no origin", []string{"*"}, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+			h := hndl.CORSMiddleware(tt.allowedOrigins)(okHandler)
+
+			r := newRequest(http.MethodGet, "/messages", "key")
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			assert.Equal(http.StatusOK, w.Code)
+			assert.Equal("Origin", w.Header().Get("Vary"))
+			assert.Equal(tt.expectedOrigin, w.Header().Get("Access-Control-Allow-Origin"))
+			if tt.expectedOrigin != "" {
+				assert.Equal("GET, POST, PUT, DELETE", w.Header().Get("Access-Control-Allow-Methods"))
+				assert.Equal("X-Api-Key, Content-Type", w.Header().Get("Access-Control-Allow-Headers"))
+			} else {
+				assert.Empty(w.Header().Get("Access-Control-Allow-Methods"))
+			}
+		})
+	}
+}
+
+func TestCORSMiddlewarePreflight(t *testing.T) {
+	tests := []struct {
+		name          string
+		origin        string
+		requestMethod string
+		preflight     bool
+	}{
+		{"allowed origin", "https://admin.tereobot.nz", http.MethodPost, true},
+		{"disallowed origin", "https://evil.example.com", http.MethodPost, false},
+		{"no origin", "", http.MethodPost, false},
+		{"not a preflight", "https://admin.tereobot.nz", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			called := false
+			h := hndl.CORSMiddleware([]string{"https://admin.tereobot.nz"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+				w.WriteHeader(http.StatusUnauthorized)
+			}))
+
+			r := httptest.NewRequest(http.MethodOptions, "/messages", nil)
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			if tt.requestMethod != "" {
+				r.Header.Set("Access-Control-Request-Method", tt.requestMethod)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			assert.Equal("Origin", w.Header().Get("Vary"))
+			if tt.preflight {
+				assert.Equal(http.StatusNoContent, w.Code)
+				assert.False(called, "preflight should not reach the api key check")
+				assert.Equal(tt.origin, w.Header().Get("Access-Control-Allow-Origin"))
+			} else {
+				assert.Equal(http.StatusUnauthorized, w.Code)
+				assert.True(called, "only preflights from allowed origins are answered by the middleware")
+			}
+		})
+	}
+}
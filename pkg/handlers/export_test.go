@@ -0,0 +1,4 @@
+package handlers
+
+// CommonMiddleware exposes the api key middleware to the tests
+var CommonMiddleware = commonMiddleware
@@ -4,49 +4,96 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io/fs"
 	"log"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/kelseyhightower/envconfig"
 	ent "github.com/wizact/te-reo-bot/pkg/entities"
+	wotd "github.com/wizact/te-reo-bot/pkg/wotd"
 )
 
 const (
 	healthCheckRoute = "/__health-check"
 	messagesRoute    = "/messages"
+	metricsRoute     = "/metrics"
+
+	embeddedDictionaryName = "dictionary.json"
 )
 
-// StartServer starts the http server
-func StartServer(address, port string, tls bool) {
+// StartServer starts the http server. The dictionary is read from dictFS unless TEREOBOT_DICT_PATH is set.
+func StartServer(address, port string, tls bool, dictFS fs.FS) {
 	serverAddress := fmt.Sprintf("%s:%s", address, port)
 
 	fmt.Println("Listening to requests from: " + serverAddress)
 
+	var sc ServerConfig
+	if err := envconfig.Process("tereobot", &sc); err != nil {
+		log.Fatal("Cannot read the server configuration from environment variables")
+	}
+
 	router := mux.NewRouter()
-	router.Use(commonMiddleware)
+	router.Use(
+		RouteTemplateMiddleware,
+		CompressionMiddleware(sc.CompressionMinSizeBytes),
+		TimeoutMiddleware(time.Duration(sc.RequestTimeoutSeconds)*time.Second),
+		commonMiddleware,
+		RateLimitMiddleware(sc.RateLimitRPM),
+		BodyLimitMiddleware(sc.MaxRequestBodyBytes),
+	)
 
 	// HealthCheck route setup
 	hcr := HealthCheckRoute{}
 	hcr.SetupRoutes(healthCheckRoute, router)
 
+	// MetricsRoute route setup
+	mtr := MetricsRoute{metrics: DefaultMetrics}
+	mtr.SetupRoutes(metricsRoute, router)
+	wotd.SetPostRecorder(DefaultMetrics)
+
 	// MessageRoute route setup
 	bn, err := (&StorageConfig{}).GetBucketName()
 	if err != nil {
 		log.Fatal("Cannot get the bucket name from environment variables")
 	}
 
-	mr := MessagesRoute{bucketName: bn}
+	var mc wotd.MastodonCredential
+	if err := envconfig.Process("tereobot", &mc); err != nil {
+		log.Fatal("Cannot read the mastodon configuration from environment variables")
+	}
+	if err := mc.Validate(); err != nil {
+		log.Fatalf("Invalid mastodon configuration: %v", err)
+	}
+
+	var dc DictionaryConfig
+	if err := envconfig.Process("tereobot", &dc); err != nil {
+		log.Fatal("Cannot read the dictionary configuration from environment variables")
+	}
+
+	mr := MessagesRoute{bucketName: bn, dictionaryPath: dc.Path, wordSelector: wotd.NewCachingWordSelector(dc.CacheTTL)}
+	if dc.Path == "" {
+		mr.dictionaryPath = embeddedDictionaryName
+		mr.wordSelector.WithFS(dictFS)
+	}
 	mr.SetupRoutes(messagesRoute, router)
 
+	var handler http.Handler = router
+	if len(sc.CORSOrigins) > 0 {
+		handler = CORSMiddleware(sc.CORSOrigins)(handler)
+	}
+	// mux only runs router.Use middleware for matched routes, so the logger wraps everything to log 404 and 405 too
+	handler = RequestLoggingMiddleware(handler)
+
 	if tls {
 		log.Fatal(http.ListenAndServeTLS(serverAddress,
 			"certs/server.crt",
 			"certs/server.key",
-			router))
+			handler))
 	} else {
-		log.Fatal(http.ListenAndServe(serverAddress, router))
+		log.Fatal(http.ListenAndServe(serverAddress, handler))
 	}
 }
 
@@ -60,7 +107,7 @@ func commonMiddleware(next http.Handler) http.Handler {
 	}
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if strings.Index(r.RequestURI, healthCheckRoute) != 0 {
+		if strings.Index(r.RequestURI, healthCheckRoute) != 0 && r.URL.Path != metricsRoute {
 			rak, err := findCaseInsensitiveHeader("X-Api-Key", r)
 
 			if err != nil {
@@ -113,7 +160,18 @@ func (fn appHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 // ServerConfig to wrap configuration
 type ServerConfig struct {
-	ApiKey string
+	ApiKey                  string
+	RateLimitRPM            int      `envconfig:"RATE_LIMIT_RPM" default:"60"`
+	CORSOrigins             []string `envconfig:"CORS_ORIGINS"`
+	RequestTimeoutSeconds   int      `envconfig:"REQUEST_TIMEOUT_SECONDS" default:"30"`
+	MaxRequestBodyBytes     int64    `envconfig:"MAX_REQUEST_BODY_BYTES" default:"65536"`
+	CompressionMinSizeBytes int      `envconfig:"COMPRESSION_MIN_SIZE_BYTES" default:"1024"`
+}
+
+// DictionaryConfig stores information required for loading the dictionary
+type DictionaryConfig struct {
+	Path     string        `envconfig:"DICT_PATH"`
+	CacheTTL time.Duration `envconfig:"DICTIONARY_CACHE_TTL" default:"5m"`
 }
 
 // StorageConfig stores information required for storage service
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type contextKey string
+
+const (
+	requestIDKey   contextKey = "request_id"
+	routeSlotKey   contextKey = "route"
+	unmatchedRoute            = "unmatched"
+)
+
+// routeSlot is filled in by RouteTemplateMiddleware inside the router, so RequestLoggingMiddleware,
+// which runs outside it, can label metrics with the matched route rather than the raw path
+type routeSlot struct {
+	template string
+}
+
+// statusRecorder captures the status code written by the wrapped handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sr *statusRecorder) WriteHeader(code int) {
+	if sr.status == 0 {
+		sr.status = code
+	}
+	sr.ResponseWriter.WriteHeader(code)
+}
+
+func (sr *statusRecorder) Write(b []byte) (int, error) {
+	if sr.status == 0 {
+		sr.status = http.StatusOK
+	}
+	return sr.ResponseWriter.Write(b)
+}
+
+// Status returns the written status code, a handler that writes nothing responds with 200
+func (sr *statusRecorder) Status() int {
+	if sr.status == 0 {
+		return http.StatusOK
+	}
+	return sr.status
+}
+
+// RequestLoggingMiddleware logs the method, path, status and latency of every request except the health check,
+// and records them in DefaultMetrics. It wraps the router so requests without a matching route are logged too,
+// with RouteTemplateMiddleware added to the router to label the metrics of the matched ones.
+// Requests get the id from the X-Request-Id header, or a new one, which is available through RequestIDFromContext.
+func RequestLoggingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Index(r.RequestURI, healthCheckRoute) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		start := time.Now()
+
+		rid := r.Header.Get("X-Request-Id")
+		if rid == "" {
+			rid = newRequestID()
+		}
+		w.Header().Set("X-Request-Id", rid)
+
+		slot := &routeSlot{}
+		ctx := context.WithValue(context.WithValue(r.Context(), requestIDKey, rid), routeSlotKey, slot)
+
+		sr := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(sr, r.WithContext(ctx))
+
+		elapsed := time.Since(start)
+		route := slot.template
+		if route == "" {
+			route = unmatchedRoute
+		}
+		DefaultMetrics.ObserveRequest(r.Method, route, sr.Status(), elapsed)
+
+		level := "info"
+		if sr.Status() >= 500 {
+			level = "error"
+		} else if sr.Status() >= 400 {
+			level = "warn"
+		}
+
+		log.Printf("%s: request completed, request_method: %s, request_path: %s, response_status: %d, latency_ms: %d, request_id: %s",
+			level, r.Method, r.URL.Path, sr.Status(), elapsed.Milliseconds(), rid)
+	})
+}
+
+// RouteTemplateMiddleware passes the matched route template to RequestLoggingMiddleware, add it to the router with router.Use
+func RouteTemplateMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if slot, ok := r.Context().Value(routeSlotKey).(*routeSlot); ok {
+			slot.template = routeTemplate(r)
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequestIDFromContext returns the id RequestLoggingMiddleware assigned to the request
+func RequestIDFromContext(ctx context.Context) string {
+	rid, _ := ctx.Value(requestIDKey).(string)
+	return rid
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,87 @@
+package handlers_test
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	hndl "github.com/wizact/te-reo-bot/pkg/handlers"
+)
+
+// captureLog redirects the standard logger to a buffer for the duration of the test
+func captureLog(t *testing.T) *bytes.Buffer {
+	var b bytes.Buffer
+	log.SetOutput(&b)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	return &b
+}
+
+func TestRequestLoggingMiddleware(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		level  string
+	}{
+		{"success", http.StatusOK, "info"},
+		{"client error", http.StatusBadRequest, "warn"},
+		{"server error", http.StatusInternalServerError, "error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+			logs := captureLog(t)
+
+			var rid string
+			h := hndl.RequestLoggingMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				rid = hndl.RequestIDFromContext(r.Context())
+				w.WriteHeader(tt.status)
+			}))
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/messages?dest=mastodon", nil))
+
+			assert.Equal(tt.status, w.Code)
+			assert.NotEmpty(rid)
+			assert.Equal(rid, w.Header().Get("X-Request-Id"))
+			assert.Contains(logs.String(), tt.level+": request completed")
+			assert.Contains(logs.String(), "request_method: POST")
+			assert.Contains(logs.String(), "request_path: /messages,")
+			assert.Contains(logs.String(), "response_status: "+strconv.Itoa(tt.status))
+			assert.Contains(logs.String(), "latency_ms: ")
+			assert.Contains(logs.String(), "request_id: "+rid)
+		})
+	}
+}
+
+func TestRequestLoggingMiddlewareKeepsRequestID(t *testing.T) {
+	assert := assert.New(t)
+	logs := captureLog(t)
+
+	h := hndl.RequestLoggingMiddleware(okHandler)
+
+	r := httptest.NewRequest(http.MethodGet, "/messages", nil)
+	r.Header.Set("X-Request-Id", "abc123")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	assert.Equal("abc123", w.Header().Get("X-Request-Id"))
+	assert.Contains(logs.String(), "response_status: 200")
+	assert.Contains(logs.String(), "request_id: abc123")
+}
+
+func TestRequestLoggingMiddlewareSkipsHealthCheck(t *testing.T) {
+	assert := assert.New(t)
+	logs := captureLog(t)
+
+	h := hndl.RequestLoggingMiddleware(okHandler)
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/__health-check", nil))
+
+	assert.Empty(logs.String())
+}
@@ -1,57 +1,86 @@
 package handlers
 
 import (
-	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/mattn/go-mastodon"
 
 	ent "github.com/wizact/te-reo-bot/pkg/entities"
 	gcs "github.com/wizact/te-reo-bot/pkg/storage"
 	wotd "github.com/wizact/te-reo-bot/pkg/wotd"
 )
 
+// dateLayout is the YYYY-MM-DD format of the date query parameter
+const dateLayout = "2006-01-02"
+
 type MessagesRoute struct {
-	bucketName string
+	bucketName     string
+	dictionaryPath string
+	wordSelector   *wotd.WordSelector
 }
 
 func (m MessagesRoute) SetupRoutes(routePath string, router *mux.Router) {
 	router.Handle(routePath, appHandler(m.PostMessage())).Methods("POST")
 	router.Handle(routePath, appHandler(m.GetImage())).Methods("GET")
+	router.Handle(routePath, appHandler(m.DeleteMessage())).Methods("DELETE")
 }
 
 // PostMessage post a message to a specific social channel
 func (m MessagesRoute) PostMessage() appHandler {
 	fn := func(w http.ResponseWriter, r *http.Request) *ent.AppError {
-		ws := wotd.WordSelector{}
-		f, erf := ws.ReadFile("./dictionary.json")
+		wordIndex := r.URL.Query().Get("wordIndex")
+		date := r.URL.Query().Get("date")
+		if wordIndex != "" && date != "" {
+			return &ent.AppError{Error: errors.New("both wordIndex and date are set"), Code: 400, Message: "Only one of wordIndex and date can be set"}
+		}
 
-		if erf != nil {
-			return &ent.AppError{Error: erf, Code: 500, Message: "Failed sending the word of the day"}
+		var dt time.Time
+		if date != "" {
+			var edt error
+			if dt, edt = time.Parse(dateLayout, date); edt != nil {
+				return &ent.AppError{Error: edt, Code: 400, Message: "Invalid date, expected format is YYYY-MM-DD"}
+			}
 		}
 
-		d, epf := ws.ParseFile(f)
-		if epf != nil {
-			return &ent.AppError{Error: epf, Code: 500, Message: "Failed sending the word of the day"}
+		ws := m.wordSelector
+		d, eld := ws.LoadDictionary(m.dictionaryPath)
+
+		if eld != nil {
+			return &ent.AppError{Error: eld, Code: 500, Message: "Failed sending the word of the day"}
 		}
 
 		var wo *wotd.Word
-		wordIndex := r.URL.Query().Get("wordIndex")
+		var esw *ent.AppError
 		if wind, eind := strconv.Atoi(wordIndex); eind == nil {
-			wo = ws.SelectWordByIndex(d.Words, wind)
+			wo, esw = ws.SelectWordByIndex(d.Words, wind)
+		} else if date != "" {
+			wo, esw = ws.SelectWordByDate(d.Words, dt)
 		} else {
-			wo = ws.SelectWordByDay(d.Words)
+			wo, esw = ws.SelectWordByDay(d.Words)
+		}
+
+		if esw != nil {
+			return esw
 		}
 
 		dest := r.URL.Query().Get("dest")
 		if strings.ToLower(dest) == "twitter" {
-			return wotd.Tweet(wo, w)
+			force, _ := strconv.ParseBool(r.URL.Query().Get("force"))
+			return wotd.Tweet(r.Context(), wo, w, force)
 		} else if strings.ToLower(dest) == "mastodon" {
 			mastodonClient := wotd.MastodonClient{}
-			return mastodonClient.NewClient().Toot(wo, w, m.bucketName)
+			opts := wotd.TootOptions{
+				ContentWarning: r.URL.Query().Get("cw"),
+				Language:       r.URL.Query().Get("lang"),
+				Visibility:     r.URL.Query().Get("visibility"),
+			}
+			return mastodonClient.NewClient().Toot(r.Context(), wo, w, m.bucketName, opts)
 		} else {
 			json.NewEncoder(w).Encode(&ent.PostResponse{Message: "No destination has been selected"})
 			return nil
@@ -61,25 +90,55 @@ func (m MessagesRoute) PostMessage() appHandler {
 	return fn
 }
 
+// DeleteMessage deletes a message previously posted to a specific social channel
+func (m MessagesRoute) DeleteMessage() appHandler {
+	fn := func(w http.ResponseWriter, r *http.Request) *ent.AppError {
+		dest := r.URL.Query().Get("dest")
+		id := r.URL.Query().Get("id")
+
+		if strings.ToLower(dest) != "mastodon" {
+			return &ent.AppError{Error: errors.New("unsupported destination: " + dest), Code: 400, Message: "Only mastodon messages can be deleted"}
+		}
+
+		if id == "" {
+			return &ent.AppError{Error: errors.New("missing message id"), Code: 400, Message: "The id of the message is required"}
+		}
+
+		if !wotd.IsTootID(id) {
+			return &ent.AppError{Error: errors.New("invalid message id: " + id), Code: 400, Message: "The id of the message must be numeric"}
+		}
+
+		mastodonClient := wotd.MastodonClient{}
+		if err := mastodonClient.NewClient().DeleteToot(r.Context(), mastodon.ID(id)); err != nil {
+			return err
+		}
+
+		json.NewEncoder(w).Encode(&ent.PostResponse{TootId: id, Message: "The message has been deleted"})
+		return nil
+	}
+
+	return fn
+}
+
 // GetImage gets the image based on the provided name from the cloud storage
 func (m MessagesRoute) GetImage() appHandler {
 	fn := func(w http.ResponseWriter, r *http.Request) *ent.AppError {
 		fn := r.URL.Query().Get("fn")
 		var cscw gcs.GoogleCloudStorageClientWrapper
-		err := cscw.Client(context.Background())
+		err := cscw.Client(r.Context())
 
 		if err != nil {
 			return &ent.AppError{Error: err, Code: 500, Message: "Failed to acquire image"}
 		}
 
-		b, err := cscw.GetObject(context.Background(), m.bucketName, fn)
+		b, err := cscw.GetObject(r.Context(), m.bucketName, fn)
 
 		if err != nil {
 			return &ent.AppError{Error: err, Code: 500, Message: "Failed to acquire image"}
 		}
 
+		w.Header().Set("Content-Type", http.DetectContentType(b))
 		w.WriteHeader(http.StatusOK)
-		w.Header().Set("Content-Type", "application/octet-stream")
 		w.Write(b)
 
 		return nil
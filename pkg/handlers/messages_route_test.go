@@ -0,0 +1,67 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	hndl "github.com/wizact/te-reo-bot/pkg/handlers"
+)
+
+func TestPostMessageRejectsInvalidSelection(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		message string
+	}{
+		{"word index and date", "?dest=mastodon&wordIndex=3&date=2024-03-15", "Only one of wordIndex and date can be set"},
+		{"invalid date", "?dest=mastodon&date=15-03-2024", "Invalid date, expected format is YYYY-MM-DD"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+			captureLog(t)
+
+			w := httptest.NewRecorder()
+			hndl.MessagesRoute{}.PostMessage().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/messages"+tt.query, nil))
+
+			assert.Equal(http.StatusBadRequest, w.Code)
+			assert.JSONEq(`{"message":"`+tt.message+`"}`, w.Body.String())
+		})
+	}
+}
+
+func TestDeleteMessageRejectsInvalidID(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		message string
+	}{
+		{"missing id", "?dest=mastodon", "The id of the message is required"},
+		{"path traversal", "?dest=mastodon&id=../filters/5", "The id of the message must be numeric"},
+		{"escaped path traversal", "?dest=mastodon&id=%2E%2E%2Ffilters%2F5", "The id of the message must be numeric"},
+		{"not a number", "?dest=mastodon&id=12a", "The id of the message must be numeric"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+			captureLog(t)
+
+			// the server would fail the test if the handler called mastodon
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				t.Errorf("unexpected request %v %v", r.Method, r.URL.Path)
+			}))
+			defer srv.Close()
+			t.Setenv("TEREOBOT_MASTODONSERVERNAME", srv.URL)
+
+			w := httptest.NewRecorder()
+			hndl.MessagesRoute{}.DeleteMessage().ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/messages"+tt.query, nil))
+
+			assert.Equal(http.StatusBadRequest, w.Code)
+			assert.JSONEq(`{"message":"`+tt.message+`"}`, w.Body.String())
+		})
+	}
+}
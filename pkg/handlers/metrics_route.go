@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics holds the prometheus collectors exposed on the metrics route
+type Metrics struct {
+	Registry            *prometheus.Registry
+	HTTPRequests        *prometheus.CounterVec
+	HTTPRequestDuration *prometheus.HistogramVec
+	Posts               *prometheus.CounterVec
+}
+
+// DefaultMetrics is the registry the server middleware and routes record to
+var DefaultMetrics = NewMetrics()
+
+// NewMetrics creates the collectors on their own registry rather than the prometheus global one
+func NewMetrics() *Metrics {
+	m := &Metrics{
+		Registry: prometheus.NewRegistry(),
+		HTTPRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "tereobot_http_requests_total",
+			Help: "Number of http requests by method, path and status code.",
+		}, []string{"method", "path", "status_code"}),
+		HTTPRequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "tereobot_http_request_duration_seconds",
+			Help:    "Duration of http requests by method and path.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "path"}),
+		Posts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "tereobot_post_total",
+			Help: "Number of words posted by platform and outcome.",
+		}, []string{"platform", "success"}),
+	}
+
+	m.Registry.MustRegister(m.HTTPRequests, m.HTTPRequestDuration, m.Posts)
+
+	return m
+}
+
+// ObserveRequest records a completed http request
+func (m *Metrics) ObserveRequest(method, path string, status int, elapsed time.Duration) {
+	m.HTTPRequests.WithLabelValues(method, path, strconv.Itoa(status)).Inc()
+	m.HTTPRequestDuration.WithLabelValues(method, path).Observe(elapsed.Seconds())
+}
+
+// ObservePost records a post to twitter or mastodon
+func (m *Metrics) ObservePost(platform string, success bool) {
+	m.Posts.WithLabelValues(platform, strconv.FormatBool(success)).Inc()
+}
+
+type MetricsRoute struct {
+	metrics *Metrics
+}
+
+func (mr MetricsRoute) SetupRoutes(routePath string, router *mux.Router) {
+	router.Handle(routePath, promhttp.HandlerFor(mr.metrics.Registry, promhttp.HandlerOpts{})).Methods("GET")
+}
+
+// routeTemplate returns the matched route path so ids in the url do not create new series
+func routeTemplate(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if t, err := route.GetPathTemplate(); err == nil {
+			return t
+		}
+	}
+
+	return r.URL.Path
+}
@@ -0,0 +1,92 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	hndl "github.com/wizact/te-reo-bot/pkg/handlers"
+)
+
+func TestMetrics(t *testing.T) {
+	assert := assert.New(t)
+	m := hndl.NewMetrics()
+
+	assert.Equal(0, testutil.CollectAndCount(m.HTTPRequests))
+
+	m.ObserveRequest(http.MethodPost, "/messages", http.StatusOK, 20*time.Millisecond)
+	m.ObserveRequest(http.MethodPost, "/messages", http.StatusOK, 30*time.Millisecond)
+	m.ObserveRequest(http.MethodPost, "/messages", http.StatusBadRequest, time.Millisecond)
+	m.ObservePost("mastodon", true)
+	m.ObservePost("twitter", false)
+
+	assert.Equal(2, testutil.CollectAndCount(m.HTTPRequests, "tereobot_http_requests_total"))
+	assert.Equal(1, testutil.CollectAndCount(m.HTTPRequestDuration, "tereobot_http_request_duration_seconds"))
+	assert.Equal(2, testutil.CollectAndCount(m.Posts, "tereobot_post_total"))
+	assert.Equal(float64(2), testutil.ToFloat64(m.HTTPRequests.WithLabelValues(http.MethodPost, "/messages", "200")))
+	assert.Equal(float64(1), testutil.ToFloat64(m.Posts.WithLabelValues("twitter", "false")))
+}
+
+func TestRequestLoggingMiddlewareRecordsMetrics(t *testing.T) {
+	router := mux.NewRouter()
+	router.Use(hndl.RouteTemplateMiddleware)
+	router.Handle("/words/{id}", okHandler).Methods(http.MethodGet)
+	h := hndl.RequestLoggingMiddleware(router)
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		labels []string
+		level  string
+	}{
+		{"matched route", http.MethodGet, "/words/42", []string{http.MethodGet, "/words/{id}", "200"}, "info"},
+		{"no matching route", http.MethodGet, "/nowhere", []string{http.MethodGet, "unmatched", "404"}, "warn"},
+		{"method not allowed", http.MethodDelete, "/words/42", []string{http.MethodDelete, "unmatched", "405"}, "warn"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+			logs := captureLog(t)
+
+			counter := hndl.DefaultMetrics.HTTPRequests.WithLabelValues(tt.labels...)
+			before := testutil.ToFloat64(counter)
+
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tt.method, tt.target, nil))
+
+			assert.Equal(before+1, testutil.ToFloat64(counter))
+			assert.Contains(logs.String(), tt.level+": request completed")
+			assert.Contains(logs.String(), "request_path: "+tt.target+",")
+			assert.Contains(logs.String(), "response_status: "+tt.labels[2])
+		})
+	}
+}
+
+func TestMetricsRouteSkipsOnlyTheExactPath(t *testing.T) {
+	t.Setenv("TEREOBOT_APIKEY", "secret")
+	h := hndl.CommonMiddleware(okHandler)
+
+	tests := []struct {
+		target   string
+		expected int
+	}{
+		{"/metrics", http.StatusOK},
+		{"/metrics?name[]=tereobot_post_total", http.StatusOK},
+		{"/metricsanything", http.StatusUnauthorized},
+		{"/metrics/../messages", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+			assert.Equal(t, tt.expected, w.Code)
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"golang.org/x/time/rate"
+
+	ent "github.com/wizact/te-reo-bot/pkg/entities"
+)
+
+// RateLimitMiddleware limits each api key to requestsPerMinute requests, allowing bursts of up to a minute's worth.
+// Rate limiting is disabled when requestsPerMinute is not positive.
+func RateLimitMiddleware(requestsPerMinute int) mux.MiddlewareFunc {
+	var limiters sync.Map
+
+	return func(next http.Handler) http.Handler {
+		if requestsPerMinute <= 0 {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.Index(r.RequestURI, healthCheckRoute) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// the key is hashed so the limiter map never holds the api keys themselves
+			h := sha256.Sum256([]byte(r.Header.Get("X-Api-Key")))
+			key := hex.EncodeToString(h[:])
+
+			l, ok := limiters.Load(key)
+			if !ok {
+				l, _ = limiters.LoadOrStore(key, rate.NewLimiter(rate.Every(time.Minute/time.Duration(requestsPerMinute)), requestsPerMinute))
+			}
+			limiter := l.(*rate.Limiter)
+
+			if !limiter.Allow() {
+				res := limiter.Reserve()
+				delay := res.Delay()
+				res.Cancel()
+
+				log.Printf("warn: rate limit exceeded, request_path: %s, retry_after_ms: %d", r.URL.Path, delay.Milliseconds())
+
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+				w.WriteHeader(http.StatusTooManyRequests)
+				json.NewEncoder(w).Encode(&ent.FriendlyError{Message: "too many requests"})
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	hndl "github.com/wizact/te-reo-bot/pkg/handlers"
+)
+
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+})
+
+func newRequest(method, target, apiKey string) *http.Request {
+	r := httptest.NewRequest(method, target, nil)
+	if apiKey != "" {
+		r.Header.Set("X-Api-Key", apiKey)
+	}
+
+	return r
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	assert := assert.New(t)
+
+	h := hndl.RateLimitMiddleware(60)(okHandler)
+
+	for i := 1; i <= 60; i++ {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, newRequest(http.MethodPost, "/messages", "key-1"))
+		assert.Equal(http.StatusOK, w.Code, "request %d", i)
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, newRequest(http.MethodPost, "/messages", "key-1"))
+	assert.Equal(http.StatusTooManyRequests, w.Code)
+
+	ra, err := strconv.Atoi(w.Header().Get("Retry-After"))
+	assert.Nil(err)
+	assert.True(ra >= 1 && ra <= 60, "unexpected Retry-After %d", ra)
+
+	// every api key has its own limit
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, newRequest(http.MethodPost, "/messages", "key-2"))
+	assert.Equal(http.StatusOK, w.Code)
+}
+
+func TestRateLimitMiddlewareSkipsHealthCheck(t *testing.T) {
+	assert := assert.New(t)
+
+	h := hndl.RateLimitMiddleware(1)(okHandler)
+
+	for i := 0; i < 5; i++ {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, newRequest(http.MethodGet, "/__health-check", ""))
+		assert.Equal(http.StatusOK, w.Code)
+	}
+}
+
+func TestRateLimitMiddlewareDisabled(t *testing.T) {
+	assert := assert.New(t)
+
+	h := hndl.RateLimitMiddleware(0)(okHandler)
+
+	for i := 0; i < 100; i++ {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, newRequest(http.MethodPost, "/messages", "key-1"))
+		assert.Equal(http.StatusOK, w.Code)
+	}
+}
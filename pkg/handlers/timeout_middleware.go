@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const timeoutMessage = `{"message":"request timed out"}`
+
+// TimeoutMiddleware responds with 503 when the handler does not finish within timeout, timeout <= 0 disables it.
+// It must run inside RequestLoggingMiddleware so the timed out requests are also logged with their status.
+func TimeoutMiddleware(timeout time.Duration) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		if timeout <= 0 {
+			return next
+		}
+
+		th := http.TimeoutHandler(next, timeout, timeoutMessage)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			sr := &statusRecorder{ResponseWriter: w}
+			th.ServeHTTP(sr, r)
+
+			if sr.Status() == http.StatusServiceUnavailable && time.Since(start) >= timeout {
+				log.Printf("error: request timed out, request_path: %s, timeout_ms: %d", r.URL.Path, timeout.Milliseconds())
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	hndl "github.com/wizact/te-reo-bot/pkg/handlers"
+)
+
+func TestTimeoutMiddleware(t *testing.T) {
+	assert := assert.New(t)
+	logs := captureLog(t)
+
+	release := make(chan struct{})
+	defer close(release)
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-time.After(5 * time.Second):
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	timeout := 50 * time.Millisecond
+	h := hndl.RequestLoggingMiddleware(hndl.TimeoutMiddleware(timeout)(slow))
+
+	start := time.Now()
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/messages/image", nil))
+	elapsed := time.Since(start)
+
+	assert.Equal(http.StatusServiceUnavailable, w.Code)
+	assert.Less(int64(elapsed), int64(timeout+500*time.Millisecond))
+	assert.Contains(logs.String(), "error: request timed out, request_path: /messages/image, timeout_ms: 50")
+	assert.Contains(logs.String(), "error: request completed")
+	assert.Contains(logs.String(), "response_status: 503")
+}
+
+func TestTimeoutMiddlewareCancelsHandlerContext(t *testing.T) {
+	assert := assert.New(t)
+	captureLog(t)
+
+	done := make(chan error, 1)
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+			done <- r.Context().Err()
+		case <-time.After(5 * time.Second):
+			done <- nil
+		}
+	})
+
+	w := httptest.NewRecorder()
+	hndl.TimeoutMiddleware(50*time.Millisecond)(slow).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/messages", nil))
+
+	assert.Equal(http.StatusServiceUnavailable, w.Code)
+	select {
+	case err := <-done:
+		assert.Equal(context.DeadlineExceeded, err)
+	case <-time.After(time.Second):
+		t.Fatal("handler kept running after the timeout")
+	}
+}
+
+func TestTimeoutMiddlewareFastHandler(t *testing.T) {
+	assert := assert.New(t)
+	logs := captureLog(t)
+
+	h := hndl.TimeoutMiddleware(time.Second)(okHandler)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/messages", nil))
+
+	assert.Equal(http.StatusOK, w.Code)
+	assert.NotContains(logs.String(), "timed out")
+}
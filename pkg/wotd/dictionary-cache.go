@@ -0,0 +1,126 @@
+package wotd
+
+import (
+	"container/list"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+)
+
+// cacheKey identifies a dictionary by the file system it is read from and its path,
+// so the embedded dictionary.json and one on the host never share an entry.
+// fsID is 0 for the host file system, see WordSelector.WithFS.
+type cacheKey struct {
+	fsID uint64
+	path string
+}
+
+// fsIDs hands out the ids of the file systems set with WithFS
+var fsIDs uint64
+
+// dictionaryCacheCapacity is the number of dictionaries kept in memory. The server loads a single dictionary,
+// so a few entries let tools and tests switch between dictionaries without reloading them
+const dictionaryCacheCapacity = 4
+
+type cacheEntry struct {
+	key      cacheKey
+	modTime  time.Time
+	loadedAt time.Time
+	dict     *Dictionary
+}
+
+// dictionaryCache is a least recently used cache of parsed dictionaries shared by all caching word selectors.
+// A plain mutex guards it because a lookup also moves the entry to the front.
+type dictionaryCache struct {
+	mu       sync.Mutex
+	capacity int
+	entries  map[cacheKey]*list.Element
+	order    *list.List
+}
+
+func newDictionaryCache(capacity int) *dictionaryCache {
+	return &dictionaryCache{capacity: capacity, entries: map[cacheKey]*list.Element{}, order: list.New()}
+}
+
+var cache = newDictionaryCache(dictionaryCacheCapacity)
+
+// get returns the cached dictionary if it belongs to the key, is younger than ttl and the file has not changed since
+func (dc *dictionaryCache) get(key cacheKey, modTime time.Time, ttl time.Duration) *Dictionary {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	el, ok := dc.entries[key]
+	if !ok {
+		return nil
+	}
+
+	e := el.Value.(*cacheEntry)
+	if !e.modTime.Equal(modTime) || time.Since(e.loadedAt) > ttl {
+		return nil
+	}
+
+	dc.order.MoveToFront(el)
+
+	return e.dict
+}
+
+// set stores the dictionary, evicting the least recently used one when the cache is full
+func (dc *dictionaryCache) set(key cacheKey, modTime time.Time, dict *Dictionary) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	e := &cacheEntry{key: key, modTime: modTime, loadedAt: time.Now(), dict: dict}
+	if el, ok := dc.entries[key]; ok {
+		el.Value = e
+		dc.order.MoveToFront(el)
+		return
+	}
+
+	dc.entries[key] = dc.order.PushFront(e)
+
+	for dc.order.Len() > dc.capacity {
+		oldest := dc.order.Back()
+		dc.order.Remove(oldest)
+		delete(dc.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+func (dc *dictionaryCache) invalidate() {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	dc.entries = map[cacheKey]*list.Element{}
+	dc.order.Init()
+}
+
+// InvalidateDictionaryCache drops the cached dictionaries so the next loads read them again
+func InvalidateDictionaryCache() {
+	cache.invalidate()
+}
+
+// InvalidateDictionaryCacheOnSignal invalidates the dictionary cache whenever one of the signals is received.
+// The returned function stops listening for the signals.
+func InvalidateDictionaryCacheOnSignal(sig ...os.Signal) func() {
+	c := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(c, sig...)
+
+	go func() {
+		for {
+			select {
+			case s := <-c:
+				log.Printf("info: invalidating dictionary cache, signal: %v", s)
+				InvalidateDictionaryCache()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(c)
+		close(done)
+	}
+}
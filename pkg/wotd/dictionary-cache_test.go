@@ -0,0 +1,206 @@
+package wotd_test
+
+import (
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	wotd "github.com/wizact/te-reo-bot/pkg/wotd"
+)
+
+const (
+	cachedDictionary  = `{"dictionary": [{ "index": 1, "word": "āe", "meaning": "yes" }]}`
+	changedDictionary = `{"dictionary": [{ "index": 1, "word": "aha", "meaning": "what?" }]}`
+)
+
+func TestLoadDictionaryReadsFileOnceWithinTTL(t *testing.T) {
+	assert := assert.New(t)
+	wotd.InvalidateDictionaryCache()
+
+	fp, mt := writeDictionary(t, cachedDictionary)
+	ws := wotd.NewCachingWordSelector(time.Minute)
+
+	d, e := ws.LoadDictionary(fp)
+	assert.Nil(e)
+	assert.Equal("āe", d.Words[0].Word)
+
+	// change the content but keep the modification time, so only a re-read would notice
+	rewriteDictionary(t, fp, changedDictionary, mt)
+
+	for i := 0; i < 100; i++ {
+		d, e = ws.LoadDictionary(fp)
+		assert.Nil(e)
+		assert.Equal("āe", d.Words[0].Word)
+	}
+}
+
+func TestLoadDictionaryReloadsWhenFileChanges(t *testing.T) {
+	assert := assert.New(t)
+	wotd.InvalidateDictionaryCache()
+
+	fp, mt := writeDictionary(t, cachedDictionary)
+	ws := wotd.NewCachingWordSelector(time.Minute)
+
+	_, e := ws.LoadDictionary(fp)
+	assert.Nil(e)
+
+	rewriteDictionary(t, fp, changedDictionary, mt.Add(time.Second))
+
+	d, e := ws.LoadDictionary(fp)
+	assert.Nil(e)
+	assert.Equal("aha", d.Words[0].Word)
+}
+
+func TestLoadDictionaryReloadsAfterSIGHUP(t *testing.T) {
+	assert := assert.New(t)
+	wotd.InvalidateDictionaryCache()
+
+	stop := wotd.InvalidateDictionaryCacheOnSignal(syscall.SIGHUP)
+	defer stop()
+
+	fp, mt := writeDictionary(t, cachedDictionary)
+	ws := wotd.NewCachingWordSelector(time.Minute)
+
+	_, e := ws.LoadDictionary(fp)
+	assert.Nil(e)
+
+	rewriteDictionary(t, fp, changedDictionary, mt)
+
+	p, err := os.FindProcess(os.Getpid())
+	assert.Nil(err)
+	assert.Nil(p.Signal(syscall.SIGHUP))
+
+	assert.Eventually(func() bool {
+		d, e := ws.LoadDictionary(fp)
+		return e == nil && d.Words[0].Word == "aha"
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestLoadDictionaryKeepsAlternatingPaths(t *testing.T) {
+	assert := assert.New(t)
+	wotd.InvalidateDictionaryCache()
+
+	fp1, mt1 := writeDictionary(t, cachedDictionary)
+	fp2, mt2 := writeDictionary(t, changedDictionary)
+	ws := wotd.NewCachingWordSelector(time.Minute)
+
+	_, e := ws.LoadDictionary(fp1)
+	assert.Nil(e)
+	_, e = ws.LoadDictionary(fp2)
+	assert.Nil(e)
+
+	// swap the contents but keep the modification times, so only a re-read would notice
+	rewriteDictionary(t, fp1, changedDictionary, mt1)
+	rewriteDictionary(t, fp2, cachedDictionary, mt2)
+
+	for i := 0; i < 10; i++ {
+		d, e := ws.LoadDictionary(fp1)
+		assert.Nil(e)
+		assert.Equal("āe", d.Words[0].Word)
+
+		d, e = ws.LoadDictionary(fp2)
+		assert.Nil(e)
+		assert.Equal("aha", d.Words[0].Word)
+	}
+}
+
+func TestLoadDictionaryEvictsLeastRecentlyUsed(t *testing.T) {
+	assert := assert.New(t)
+	wotd.InvalidateDictionaryCache()
+
+	ws := wotd.NewCachingWordSelector(time.Minute)
+
+	first, mt := writeDictionary(t, cachedDictionary)
+	_, e := ws.LoadDictionary(first)
+	assert.Nil(e)
+	rewriteDictionary(t, first, changedDictionary, mt)
+
+	// more dictionaries than the cache holds push the first one out
+	for i := 0; i < 4; i++ {
+		fp, _ := writeDictionary(t, cachedDictionary)
+		_, e := ws.LoadDictionary(fp)
+		assert.Nil(e)
+	}
+
+	d, e := ws.LoadDictionary(first)
+	assert.Nil(e)
+	assert.Equal("aha", d.Words[0].Word)
+}
+
+func TestLoadDictionaryKeepsFileSystemsApart(t *testing.T) {
+	assert := assert.New(t)
+	wotd.InvalidateDictionaryCache()
+
+	// both selectors load "dictionary.json", one from the working directory and one from the in-memory file system
+	fp, _ := writeDictionary(t, cachedDictionary)
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(filepath.Dir(fp)); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	fsys := fstest.MapFS{"dictionary.json": &fstest.MapFile{Data: []byte(changedDictionary), ModTime: time.Now()}}
+
+	host := wotd.NewCachingWordSelector(time.Minute)
+	embedded := wotd.NewCachingWordSelector(time.Minute).WithFS(fsys)
+
+	for i := 0; i < 2; i++ {
+		d, e := host.LoadDictionary("dictionary.json")
+		assert.Nil(e)
+		assert.Equal("āe", d.Words[0].Word)
+
+		d, e = embedded.LoadDictionary("dictionary.json")
+		assert.Nil(e)
+		assert.Equal("aha", d.Words[0].Word)
+	}
+}
+
+// structFS is a file system that is not comparable and not a map, such values cannot be used as map keys
+type structFS struct {
+	files fstest.MapFS
+}
+
+func (sf structFS) Open(name string) (fs.File, error) {
+	return sf.files.Open(name)
+}
+
+func TestLoadDictionaryFromStructFS(t *testing.T) {
+	assert := assert.New(t)
+	wotd.InvalidateDictionaryCache()
+
+	fsys := structFS{fstest.MapFS{"dictionary.json": &fstest.MapFile{Data: []byte(cachedDictionary), ModTime: time.Now()}}}
+	ws := wotd.NewCachingWordSelector(time.Minute).WithFS(fsys)
+
+	for i := 0; i < 2; i++ {
+		d, e := ws.LoadDictionary("dictionary.json")
+		assert.Nil(e)
+		assert.Equal("āe", d.Words[0].Word)
+	}
+}
+
+func writeDictionary(t *testing.T, content string) (string, time.Time) {
+	fp := filepath.Join(t.TempDir(), "dictionary.json")
+	mt := time.Now().Add(-time.Hour).Truncate(time.Second)
+	rewriteDictionary(t, fp, content, mt)
+
+	return fp, mt
+}
+
+func rewriteDictionary(t *testing.T, fp, content string, mt time.Time) {
+	if err := ioutil.WriteFile(fp, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Chtimes(fp, mt, mt); err != nil {
+		t.Fatal(err)
+	}
+}
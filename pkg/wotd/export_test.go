@@ -0,0 +1,14 @@
+package wotd
+
+import (
+	"context"
+	"time"
+)
+
+// SetSleep replaces the wait between retries, the returned function restores it
+func SetSleep(f func(ctx context.Context, d time.Duration) error) func() {
+	s := sleep
+	sleep = f
+
+	return func() { sleep = s }
+}
@@ -4,7 +4,13 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
 	"net/http"
+	"regexp"
+	"strconv"
+	"time"
 
 	"github.com/kelseyhightower/envconfig"
 	"github.com/mattn/go-mastodon"
@@ -12,19 +18,72 @@ import (
 	gcs "github.com/wizact/te-reo-bot/pkg/storage"
 )
 
+const (
+	// defaultTootLanguage is te reo Māori, the language every toot is written in
+	defaultTootLanguage   = "mi"
+	defaultTootVisibility = "public"
+	defaultMaxTootLength  = 500
+)
+
+// tootVisibilities are the visibilities accepted by the Mastodon statuses api
+var tootVisibilities = map[string]bool{"public": true, "unlisted": true, "private": true, "direct": true}
+
+// languagePattern matches a two or three letter BCP47 primary language tag
+var languagePattern = regexp.MustCompile(`^[a-zA-Z]{2,3}$`)
+
+var apiErrorPattern = regexp.MustCompile(`^bad request: (\d{3}) `)
+
+// tootIDPattern matches a Mastodon status id. go-mastodon joins the id into the request path,
+// so anything else, e.g. "../filters/5", could reach another api endpoint.
+var tootIDPattern = regexp.MustCompile(`^[0-9]+$`)
+
+// retryableStatusCodes are the server errors usually caused by Mastodon maintenance, a 4xx is a problem with the request itself
+var retryableStatusCodes = map[int]bool{
+	http.StatusInternalServerError: true,
+	http.StatusBadGateway:          true,
+	http.StatusServiceUnavailable:  true,
+	http.StatusGatewayTimeout:      true,
+}
+
 type MastodonClient struct {
 	mastodonServerName  string
 	mastodonClientID    string
 	mastodonAccessToken string
+	contentWarning      string
+	language            string
+	visibility          string
+	retry               MastodonRetryConfig
+	maxTootLength       int
+	truncateOnOverflow  bool
+}
+
+// TootOptions overrides the configured toot settings for a single toot
+type TootOptions struct {
+	ContentWarning string
+	Language       string
+	Visibility     string
+}
+
+// NewMastodonClient returns a Mastodon client configured with the provided credential
+func NewMastodonClient(mc *MastodonCredential) *MastodonClient {
+	return &MastodonClient{
+		mastodonServerName:  mc.MastodonServerName,
+		mastodonClientID:    mc.MastodonClientID,
+		mastodonAccessToken: mc.MastodonAccessToken,
+		contentWarning:      mc.ContentWarning,
+		language:            mc.Language,
+		visibility:          mc.Visibility,
+		retry:               mc.Retry,
+		maxTootLength:       mc.MaxTootLength,
+		truncateOnOverflow:  mc.TruncateOnOverflow,
+	}
 }
 
 func (mclient *MastodonClient) NewClient() *MastodonClient {
 	var mc MastodonCredential
 	envconfig.Process("tereobot", &mc)
 
-	mclient.mastodonServerName = mc.MastodonServerName
-	mclient.mastodonClientID = mc.MastodonClientID
-	mclient.mastodonAccessToken = mc.MastodonAccessToken
+	*mclient = *NewMastodonClient(&mc)
 
 	return mclient
 }
@@ -39,26 +98,44 @@ func (mclient *MastodonClient) client() *mastodon.Client {
 	return c
 }
 
-func (mclient *MastodonClient) Toot(wo *Word, w http.ResponseWriter, bucketName string) *ent.AppError {
+// Toot posts the word to Mastodon, using opts to override the configured settings when they are set.
+// The image download, upload and retries stop when ctx is done.
+func (mclient *MastodonClient) Toot(ctx context.Context, wo *Word, w http.ResponseWriter, bucketName string, opts TootOptions) *ent.AppError {
+	lang := firstNonEmpty(opts.Language, mclient.language, defaultTootLanguage)
+	if err := validateLanguage(lang); err != nil {
+		return &ent.AppError{Error: err, Code: 400, Message: "Language must be a two or three letter language tag"}
+	}
+
+	vis := firstNonEmpty(opts.Visibility, mclient.visibility, defaultTootVisibility)
+	if err := validateVisibility(vis); err != nil {
+		return &ent.AppError{Error: err, Code: 400, Message: "Visibility must be one of public, unlisted, private or direct"}
+	}
+
+	status, ae := mclient.fitTootLength(wo.Word + ": " + wo.Meaning + " #aotearoa #newzealand")
+	if ae != nil {
+		return ae
+	}
+
 	var att *mastodon.Attachment
 	mids := []mastodon.ID{}
 	tc := mclient.client()
 
 	// check if the wo has a photo
 	if hasMedia(wo) {
-		media, err := acquireMedia(bucketName, wo.Photo)
+		media, err := acquireMedia(ctx, bucketName, wo.Photo)
 		if err != nil {
 			return err
 		}
 
 		var e error
 		if wo.Attribution != "" {
-			att, e = tc.UploadMediaFromMedia(context.Background(), &mastodon.Media{File: bytes.NewReader(media), Description: wo.Attribution})
+			att, e = tc.UploadMediaFromMedia(ctx, &mastodon.Media{File: bytes.NewReader(media), Description: wo.Attribution})
 		} else {
-			att, e = tc.UploadMediaFromBytes(context.Background(), media)
+			att, e = tc.UploadMediaFromBytes(ctx, media)
 		}
 
 		if e != nil {
+			recordPost("mastodon", false)
 			return &ent.AppError{Error: e, Code: 500, Message: "Failed sending the toot with media"}
 		}
 	}
@@ -67,26 +144,99 @@ func (mclient *MastodonClient) Toot(wo *Word, w http.ResponseWriter, bucketName
 		mids = []mastodon.ID{att.ID}
 	}
 
-	ms, e := tc.PostStatus(context.Background(), &mastodon.Toot{Status: wo.Word + ": " + wo.Meaning + " #aotearoa #newzealand", MediaIDs: mids})
+	cw := firstNonEmpty(opts.ContentWarning, mclient.contentWarning)
+
+	ms, e := mclient.postStatusWithRetry(ctx, tc, &mastodon.Toot{Status: status, MediaIDs: mids, SpoilerText: cw, Language: lang, Visibility: vis})
+	recordPost("mastodon", e == nil)
 
 	if e == nil {
-		json.NewEncoder(w).Encode(&ent.PostResponse{TootId: string(ms.ID)})
+		json.NewEncoder(w).Encode(&ent.PostResponse{TootId: string(ms.ID), TootURL: ms.URL})
 		return nil
 	} else {
 		return &ent.AppError{Error: e, Code: 500, Message: "Failed sending the toot"}
 	}
 }
 
-func acquireMedia(bucketName, objectName string) ([]byte, *ent.AppError) {
+// fitTootLength checks the status fits in the maximum toot length, truncating it when the client is configured to
+func (mclient *MastodonClient) fitTootLength(status string) (string, *ent.AppError) {
+	max := mclient.maxTootLength
+	if max <= 0 {
+		max = defaultMaxTootLength
+	}
+
+	r := []rune(status)
+	if len(r) <= max {
+		return status, nil
+	}
+
+	if mclient.truncateOnOverflow {
+		log.Printf("warn: truncating the toot, toot_length: %d, max_length: %d", len(r), max)
+		return string(r[:max-1]) + "…", nil
+	}
+
+	log.Printf("warn: toot exceeds the maximum length, toot_length: %d, max_length: %d", len(r), max)
+
+	return "", &ent.AppError{Error: fmt.Errorf("toot has %d characters, the maximum is %d", len(r), max), Code: 400, Message: "Toot exceeds maximum length"}
+}
+
+// postStatusWithRetry posts the toot and retries it with exponential backoff while the server responds with a 5xx gateway or server error
+func (mclient *MastodonClient) postStatusWithRetry(ctx context.Context, tc *mastodon.Client, toot *mastodon.Toot) (*mastodon.Status, error) {
+	delay := mclient.retry.InitialDelay
+
+	for attempt := 1; ; attempt++ {
+		ms, e := tc.PostStatus(ctx, toot)
+		if e == nil || !retryableStatusCodes[apiStatusCode(e)] || attempt >= mclient.retry.MaxAttempts {
+			return ms, e
+		}
+
+		log.Printf("warn: failed sending the toot, retrying, attempt: %d, error: %v", attempt, e)
+		if err := sleep(ctx, delay+time.Duration(rand.Int63n(int64(delay/4)+1))); err != nil {
+			return nil, err
+		}
+
+		delay *= 2
+		if mclient.retry.MaxDelay > 0 && delay > mclient.retry.MaxDelay {
+			delay = mclient.retry.MaxDelay
+		}
+	}
+}
+
+// IsTootID reports whether id is a valid Mastodon status id
+func IsTootID(id string) bool {
+	return tootIDPattern.MatchString(id)
+}
+
+// DeleteToot deletes a toot posted by the bot account
+func (mclient *MastodonClient) DeleteToot(ctx context.Context, tootID mastodon.ID) *ent.AppError {
+	if !IsTootID(string(tootID)) {
+		return &ent.AppError{Error: fmt.Errorf("invalid toot id %q", tootID), Code: 400, Message: "The id of the message must be numeric"}
+	}
+
+	e := mclient.client().DeleteStatus(ctx, tootID)
+
+	if e != nil {
+		if apiStatusCode(e) == http.StatusNotFound {
+			return &ent.AppError{Error: e, Code: 404, Message: "Toot not found"}
+		}
+
+		return &ent.AppError{Error: e, Code: 500, Message: "Failed deleting the toot"}
+	}
+
+	log.Printf("info: deleted toot, platform: mastodon, toot_id: %s", tootID)
+
+	return nil
+}
+
+func acquireMedia(ctx context.Context, bucketName, objectName string) ([]byte, *ent.AppError) {
 
 	var cscw gcs.GoogleCloudStorageClientWrapper
-	err := cscw.Client(context.Background())
+	err := cscw.Client(ctx)
 
 	if err != nil {
 		return nil, &ent.AppError{Error: err, Code: 500, Message: "Failed to acquire image"}
 	}
 
-	media, err := cscw.GetObject(context.Background(), bucketName, objectName)
+	media, err := cscw.GetObject(ctx, bucketName, objectName)
 
 	if err != nil {
 		return nil, &ent.AppError{Error: err, Code: 500, Message: "Failed to acquire image"}
@@ -99,9 +249,71 @@ func hasMedia(wo *Word) bool {
 	return len(wo.Photo) > 0
 }
 
+// apiStatusCode extracts the http status code from a go-mastodon error, which has the form "bad request: 404 Not Found: ..."
+func apiStatusCode(err error) int {
+	m := apiErrorPattern.FindStringSubmatch(err.Error())
+	if m == nil {
+		return 0
+	}
+
+	code, _ := strconv.Atoi(m[1])
+	return code
+}
+
+func validateLanguage(lang string) error {
+	if !languagePattern.MatchString(lang) {
+		return fmt.Errorf("invalid language tag %q", lang)
+	}
+
+	return nil
+}
+
+func validateVisibility(vis string) error {
+	if !tootVisibilities[vis] {
+		return fmt.Errorf("invalid visibility %q", vis)
+	}
+
+	return nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+
+	return ""
+}
+
 // MastodonCredential is a wrapper for consumer and access secrets
 type MastodonCredential struct {
 	MastodonServerName  string
 	MastodonClientID    string
 	MastodonAccessToken string
+	// ContentWarning is the spoiler text shown before the toot, no content warning is added when it is empty
+	ContentWarning string              `envconfig:"MASTODON_CONTENT_WARNING"`
+	Language       string              `envconfig:"MASTODON_LANGUAGE" default:"mi"`
+	Visibility     string              `envconfig:"MASTODON_VISIBILITY" default:"public"`
+	Retry          MastodonRetryConfig `envconfig:"MASTODON_RETRY"`
+	MaxTootLength  int                 `envconfig:"MASTODON_MAX_LENGTH" default:"500"`
+	// TruncateOnOverflow shortens toots longer than MaxTootLength instead of rejecting them
+	TruncateOnOverflow bool `envconfig:"MASTODON_TRUNCATE_ON_OVERFLOW"`
+}
+
+// MastodonRetryConfig configures how toots failing with a server error are retried
+type MastodonRetryConfig struct {
+	MaxAttempts  int           `envconfig:"MAX_ATTEMPTS" default:"3"`
+	InitialDelay time.Duration `envconfig:"INITIAL_DELAY" default:"1s"`
+	// MaxDelay is the longest wait between two attempts, no cap is applied when it is zero
+	MaxDelay time.Duration `envconfig:"MAX_DELAY" default:"10s"`
+}
+
+// Validate checks the configured toot settings, empty settings fall back to their defaults
+func (mc *MastodonCredential) Validate() error {
+	if err := validateLanguage(firstNonEmpty(mc.Language, defaultTootLanguage)); err != nil {
+		return err
+	}
+
+	return validateVisibility(firstNonEmpty(mc.Visibility, defaultTootVisibility))
 }
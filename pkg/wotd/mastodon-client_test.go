@@ -0,0 +1,384 @@
+package wotd_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/kelseyhightower/envconfig"
+	"github.com/stretchr/testify/assert"
+	ent "github.com/wizact/te-reo-bot/pkg/entities"
+	wotd "github.com/wizact/te-reo-bot/pkg/wotd"
+)
+
+// newMastodonServer mocks the statuses endpoint, passing the posted form to the handler
+func newMastodonServer(t *testing.T, handler func(form url.Values) (int, string)) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/statuses" || r.Method != http.MethodPost {
+			t.Errorf("unexpected request %v %v", r.Method, r.URL.Path)
+		}
+
+		if err := r.ParseForm(); err != nil {
+			t.Error(err)
+		}
+
+		code, body := handler(r.PostForm)
+		w.WriteHeader(code)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestTootContentWarning(t *testing.T) {
+	tests := []struct {
+		name       string
+		configured string
+		override   string
+		want       string
+	}{
+		{"no content warning", "", "", ""},
+		{"configured content warning", "language lesson", "", "language lesson"},
+		{"request overrides configured content warning", "language lesson", "te reo māori", "te reo māori"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			var form url.Values
+			srv := newMastodonServer(t, func(f url.Values) (int, string) {
+				form = f
+				return http.StatusOK, `{"id":"1"}`
+			})
+
+			mc := wotd.NewMastodonClient(&wotd.MastodonCredential{MastodonServerName: srv.URL, ContentWarning: tt.configured})
+
+			w := httptest.NewRecorder()
+			e := mc.Toot(context.Background(), &wotd.Word{Word: "Korimako", Meaning: "bellbird"}, w, "", wotd.TootOptions{ContentWarning: tt.override})
+			assert.Nil(e)
+
+			_, sent := form["spoiler_text"]
+			assert.Equal(tt.want != "", sent)
+			assert.Equal(tt.want, form.Get("spoiler_text"))
+
+			var pr ent.PostResponse
+			assert.Nil(json.NewDecoder(w.Body).Decode(&pr))
+			assert.Equal("1", pr.TootId)
+		})
+	}
+}
+
+func TestTootLanguage(t *testing.T) {
+	tests := []struct {
+		name       string
+		configured string
+		override   string
+		want       string
+	}{
+		{"default language", "", "", "mi"},
+		{"configured language", "en", "", "en"},
+		{"request overrides configured language", "en", "mi", "mi"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			var form url.Values
+			srv := newMastodonServer(t, func(f url.Values) (int, string) {
+				form = f
+				return http.StatusOK, `{"id":"1"}`
+			})
+
+			mc := wotd.NewMastodonClient(&wotd.MastodonCredential{MastodonServerName: srv.URL, Language: tt.configured})
+
+			e := mc.Toot(context.Background(), &wotd.Word{Word: "Korimako", Meaning: "bellbird"}, httptest.NewRecorder(), "", wotd.TootOptions{Language: tt.override})
+			assert.Nil(e)
+			assert.Equal(tt.want, form.Get("language"))
+		})
+	}
+}
+
+func TestTootRejectsInvalidLanguage(t *testing.T) {
+	assert := assert.New(t)
+
+	posts := 0
+	srv := newMastodonServer(t, func(f url.Values) (int, string) {
+		posts++
+		return http.StatusOK, `{"id":"1"}`
+	})
+
+	mc := wotd.NewMastodonClient(&wotd.MastodonCredential{MastodonServerName: srv.URL})
+
+	for _, lang := range []string{"m", "māori", "en-NZ-x", "12"} {
+		e := mc.Toot(context.Background(), &wotd.Word{Word: "Korimako", Meaning: "bellbird"}, httptest.NewRecorder(), "", wotd.TootOptions{Language: lang})
+
+		if assert.NotNil(e, lang) {
+			assert.Equal(400, e.Code, lang)
+		}
+	}
+	assert.Equal(0, posts)
+}
+
+func TestTootVisibility(t *testing.T) {
+	tests := []struct {
+		name       string
+		configured string
+		override   string
+		want       string
+	}{
+		{"default visibility", "", "", "public"},
+		{"public", "public", "", "public"},
+		{"unlisted", "unlisted", "", "unlisted"},
+		{"private", "private", "", "private"},
+		{"direct", "direct", "", "direct"},
+		{"request overrides configured visibility", "public", "unlisted", "unlisted"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			var form url.Values
+			srv := newMastodonServer(t, func(f url.Values) (int, string) {
+				form = f
+				return http.StatusOK, `{"id":"1"}`
+			})
+
+			mc := wotd.NewMastodonClient(&wotd.MastodonCredential{MastodonServerName: srv.URL, Visibility: tt.configured})
+
+			e := mc.Toot(context.Background(), &wotd.Word{Word: "Korimako", Meaning: "bellbird"}, httptest.NewRecorder(), "", wotd.TootOptions{Visibility: tt.override})
+			assert.Nil(e)
+			assert.Equal(tt.want, form.Get("visibility"))
+		})
+	}
+}
+
+func TestTootRejectsInvalidVisibility(t *testing.T) {
+	assert := assert.New(t)
+
+	posts := 0
+	srv := newMastodonServer(t, func(f url.Values) (int, string) {
+		posts++
+		return http.StatusOK, `{"id":"1"}`
+	})
+
+	mc := wotd.NewMastodonClient(&wotd.MastodonCredential{MastodonServerName: srv.URL})
+
+	e := mc.Toot(context.Background(), &wotd.Word{Word: "Korimako", Meaning: "bellbird"}, httptest.NewRecorder(), "", wotd.TootOptions{Visibility: "followers"})
+
+	if assert.NotNil(e) {
+		assert.Equal(400, e.Code)
+	}
+	assert.Equal(0, posts)
+}
+
+func TestMastodonCredentialValidate(t *testing.T) {
+	assert := assert.New(t)
+
+	var mc wotd.MastodonCredential
+	assert.Nil(envconfig.Process("tereobot", &mc))
+	assert.Equal("public", mc.Visibility)
+	assert.Equal("mi", mc.Language)
+
+	assert.Nil((&wotd.MastodonCredential{}).Validate())
+	assert.Nil((&wotd.MastodonCredential{Visibility: "unlisted", Language: "en"}).Validate())
+	assert.NotNil((&wotd.MastodonCredential{Visibility: "everyone"}).Validate())
+	assert.NotNil((&wotd.MastodonCredential{Language: "english"}).Validate())
+}
+
+func TestDeleteToot(t *testing.T) {
+	assert := assert.New(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(http.MethodDelete, r.Method)
+
+		switch r.URL.Path {
+		case "/api/v1/statuses/1":
+			w.Write([]byte(`{}`))
+		case "/api/v1/statuses/2":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":"Record not found"}`))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	mc := wotd.NewMastodonClient(&wotd.MastodonCredential{MastodonServerName: srv.URL})
+
+	assert.Nil(mc.DeleteToot(context.Background(), "1"))
+
+	e := mc.DeleteToot(context.Background(), "2")
+	if assert.NotNil(e) {
+		assert.Equal(404, e.Code)
+	}
+
+	e = mc.DeleteToot(context.Background(), "3")
+	if assert.NotNil(e) {
+		assert.Equal(500, e.Code)
+	}
+
+	// go-mastodon joins the id into the path, so "../filters/5" would delete /api/v1/filters/5
+	e = mc.DeleteToot(context.Background(), "../filters/5")
+	if assert.NotNil(e) {
+		assert.Equal(400, e.Code)
+	}
+}
+
+func TestTootRetriesServerErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	posts := 0
+	srv := newMastodonServer(t, func(f url.Values) (int, string) {
+		posts++
+		if posts <= 2 {
+			return http.StatusServiceUnavailable, `{"error":"Service Unavailable"}`
+		}
+
+		return http.StatusOK, `{"id":"3","url":"https://mastodon.nz/@tereobot/3"}`
+	})
+
+	mc := wotd.NewMastodonClient(&wotd.MastodonCredential{
+		MastodonServerName: srv.URL,
+		Retry:              wotd.MastodonRetryConfig{MaxAttempts: 5, InitialDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond},
+	})
+
+	w := httptest.NewRecorder()
+	e := mc.Toot(context.Background(), &wotd.Word{Word: "Korimako", Meaning: "bellbird"}, w, "", wotd.TootOptions{})
+	assert.Nil(e)
+	assert.Equal(3, posts, "expected exactly two retries")
+
+	var pr ent.PostResponse
+	assert.Nil(json.NewDecoder(w.Body).Decode(&pr))
+	assert.Equal("3", pr.TootId)
+	assert.Equal("https://mastodon.nz/@tereobot/3", pr.TootURL)
+}
+
+func TestTootStopsRetryingWhenContextIsDone(t *testing.T) {
+	assert := assert.New(t)
+
+	posts := 0
+	srv := newMastodonServer(t, func(f url.Values) (int, string) {
+		posts++
+		return http.StatusServiceUnavailable, `{"error":"Service Unavailable"}`
+	})
+
+	mc := wotd.NewMastodonClient(&wotd.MastodonCredential{
+		MastodonServerName: srv.URL,
+		Retry:              wotd.MastodonRetryConfig{MaxAttempts: 5, InitialDelay: time.Hour},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	e := mc.Toot(ctx, &wotd.Word{Word: "Korimako", Meaning: "bellbird"}, httptest.NewRecorder(), "", wotd.TootOptions{})
+	assert.NotNil(e)
+	assert.Less(int64(time.Since(start)), int64(time.Second))
+	assert.Equal(1, posts, "no toot should be posted after the context is done")
+}
+
+func TestTootDoesNotRetryClientErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	posts := 0
+	srv := newMastodonServer(t, func(f url.Values) (int, string) {
+		posts++
+		return http.StatusUnprocessableEntity, `{"error":"Validation failed: Text can't be blank"}`
+	})
+
+	mc := wotd.NewMastodonClient(&wotd.MastodonCredential{
+		MastodonServerName: srv.URL,
+		Retry:              wotd.MastodonRetryConfig{MaxAttempts: 5, InitialDelay: time.Millisecond},
+	})
+
+	e := mc.Toot(context.Background(), &wotd.Word{Word: "Korimako", Meaning: "bellbird"}, httptest.NewRecorder(), "", wotd.TootOptions{})
+	assert.NotNil(e)
+	assert.Equal(1, posts)
+}
+
+func TestTootMaxLength(t *testing.T) {
+	// the status is "<word>: <meaning> #aotearoa #newzealand", 30 characters plus the meaning
+	const max = 50
+
+	tests := []struct {
+		name     string
+		meaning  string
+		truncate bool
+		valid    bool
+		want     string
+	}{
+		{"at the limit", strings.Repeat("ā", 20), false, true, "kōwhai: " + strings.Repeat("ā", 20) + " #aotearoa #newzealand"},
+		{"one over the limit", strings.Repeat("ā", 21), false, false, ""},
+		{"one over the limit truncated", strings.Repeat("ā", 21), true, true, "kōwhai: " + strings.Repeat("ā", 21) + " #aotearoa #newzeala…"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			var form url.Values
+			srv := newMastodonServer(t, func(f url.Values) (int, string) {
+				form = f
+				return http.StatusOK, `{"id":"1"}`
+			})
+
+			mc := wotd.NewMastodonClient(&wotd.MastodonCredential{MastodonServerName: srv.URL, MaxTootLength: max, TruncateOnOverflow: tt.truncate})
+
+			e := mc.Toot(context.Background(), &wotd.Word{Word: "kōwhai", Meaning: tt.meaning}, httptest.NewRecorder(), "", wotd.TootOptions{})
+
+			if !tt.valid {
+				if assert.NotNil(e) {
+					assert.Equal(400, e.Code)
+					assert.Equal("Toot exceeds maximum length", e.Message)
+				}
+				assert.Nil(form)
+				return
+			}
+
+			assert.Nil(e)
+			assert.Equal(tt.want, form.Get("status"))
+			assert.Equal(max, len([]rune(form.Get("status"))))
+			assert.True(utf8.ValidString(form.Get("status")))
+		})
+	}
+}
+
+// postCounter records the posts reported to it
+type postCounter map[string]int
+
+func (pc postCounter) ObservePost(platform string, success bool) {
+	pc[platform+" "+strconv.FormatBool(success)]++
+}
+
+func TestTootRecordsOnlySentPosts(t *testing.T) {
+	assert := assert.New(t)
+
+	pc := postCounter{}
+	wotd.SetPostRecorder(pc)
+	defer wotd.SetPostRecorder(nil)
+
+	srv := newMastodonServer(t, func(f url.Values) (int, string) {
+		return http.StatusOK, `{"id":"1","url":"https://mastodon.nz/@tereobot/1"}`
+	})
+	mc := wotd.NewMastodonClient(&wotd.MastodonCredential{MastodonServerName: srv.URL})
+
+	// rejected before sending
+	e := mc.Toot(context.Background(), &wotd.Word{Word: "Korimako", Meaning: "bellbird"}, httptest.NewRecorder(), "", wotd.TootOptions{Visibility: "followers"})
+	assert.NotNil(e)
+	assert.Empty(pc)
+
+	e = mc.Toot(context.Background(), &wotd.Word{Word: "Korimako", Meaning: "bellbird"}, httptest.NewRecorder(), "", wotd.TootOptions{})
+	assert.Nil(e)
+	assert.Equal(postCounter{"mastodon true": 1}, pc)
+}
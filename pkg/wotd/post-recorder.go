@@ -0,0 +1,31 @@
+package wotd
+
+import "sync"
+
+// PostRecorder is told the outcome of every post sent to twitter or mastodon, e.g. to count them in metrics.
+// Requests rejected before anything is sent, and dry runs, are not recorded.
+type PostRecorder interface {
+	ObservePost(platform string, success bool)
+}
+
+var (
+	recorderMu   sync.RWMutex
+	postRecorder PostRecorder
+)
+
+// SetPostRecorder sets the recorder told about sent posts, nil stops recording
+func SetPostRecorder(r PostRecorder) {
+	recorderMu.Lock()
+	defer recorderMu.Unlock()
+
+	postRecorder = r
+}
+
+func recordPost(platform string, success bool) {
+	recorderMu.RLock()
+	defer recorderMu.RUnlock()
+
+	if postRecorder != nil {
+		postRecorder.ObservePost(platform, success)
+	}
+}
@@ -0,0 +1,85 @@
+package wotd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"time"
+
+	ent "github.com/wizact/te-reo-bot/pkg/entities"
+)
+
+// daysInYear is the number of days a schedule covers, including the leap day
+const daysInYear = 366
+
+// Schedule maps each day of the year to the position of a word in the dictionary
+type Schedule struct {
+	Seed int64       `json:"seed"`
+	Days map[int]int `json:"days"`
+}
+
+// GenerateSchedule shuffles the words over the days of the year. The same seed always produces the same schedule.
+// A dictionary with more words than days leaves the words that were not picked out of this year's schedule,
+// one with fewer words than days repeats words as evenly as possible.
+func GenerateSchedule(words []Word, seed int64) *Schedule {
+	s := &Schedule{Seed: seed, Days: map[int]int{}}
+	if len(words) == 0 {
+		return s
+	}
+
+	// shuffle every word so words past the first 366 can be scheduled too,
+	// a dictionary smaller than a year is shuffled again for each pass through it
+	r := rand.New(rand.NewSource(seed))
+	positions := make([]int, 0, daysInYear+len(words))
+	for len(positions) < daysInYear {
+		positions = append(positions, r.Perm(len(words))...)
+	}
+
+	for i, p := range positions[:daysInYear] {
+		s.Days[i+1] = p
+	}
+
+	return s
+}
+
+// SaveSchedule writes the schedule as a json file
+func SaveSchedule(s *Schedule, path string) error {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, b, 0644)
+}
+
+// LoadSchedule reads a schedule json file written by SaveSchedule
+func LoadSchedule(path string) (*Schedule, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	s := Schedule{}
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+// SelectWordFromSchedule selects the word scheduled for the day of the year of the date
+func SelectWordFromSchedule(s *Schedule, words []Word, date time.Time) (*Word, *ent.AppError) {
+	p, ok := s.Days[date.YearDay()]
+	if !ok || p < 0 || p >= len(words) {
+		return nil, &ent.AppError{Error: fmt.Errorf("no word scheduled for day %d", date.YearDay()), Code: 500, Message: "Failed selecting the word of the day"}
+	}
+
+	var ws WordSelector
+	w := &words[p]
+	if err := ws.validateSelectedWord(w); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
@@ -0,0 +1,104 @@
+package wotd_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	wotd "github.com/wizact/te-reo-bot/pkg/wotd"
+)
+
+func TestGenerateScheduleCoversEveryDayOnce(t *testing.T) {
+	assert := assert.New(t)
+
+	s := wotd.GenerateSchedule(buildWords(366), 12345)
+
+	assert.Len(s.Days, 366)
+
+	seen := map[int]bool{}
+	for day := 1; day <= 366; day++ {
+		p, ok := s.Days[day]
+		assert.True(ok, "day %d is not scheduled", day)
+		assert.False(seen[p], "word %d is scheduled twice", p)
+		seen[p] = true
+	}
+	assert.Len(seen, 366)
+}
+
+func TestGenerateScheduleCanPickAnyWordOfLargeDictionary(t *testing.T) {
+	assert := assert.New(t)
+
+	s := wotd.GenerateSchedule(buildWords(1000), 12345)
+
+	seen := map[int]bool{}
+	beyondYear := 0
+	for _, p := range s.Days {
+		assert.False(seen[p], "word %d is scheduled twice", p)
+		seen[p] = true
+		if p >= 366 {
+			beyondYear++
+		}
+	}
+	assert.Len(seen, 366)
+	assert.Greater(beyondYear, 0, "words past the first 366 are never scheduled")
+}
+
+func TestGenerateScheduleIsDeterministic(t *testing.T) {
+	assert := assert.New(t)
+
+	words := buildWords(366)
+
+	assert.Equal(wotd.GenerateSchedule(words, 12345), wotd.GenerateSchedule(words, 12345))
+	assert.NotEqual(wotd.GenerateSchedule(words, 12345).Days, wotd.GenerateSchedule(words, 54321).Days)
+}
+
+func TestGenerateScheduleRepeatsWordsOfSmallDictionary(t *testing.T) {
+	assert := assert.New(t)
+
+	s := wotd.GenerateSchedule(buildWords(100), 1)
+
+	counts := map[int]int{}
+	for _, p := range s.Days {
+		counts[p]++
+	}
+
+	assert.Len(counts, 100)
+	for p, c := range counts {
+		assert.True(c == 3 || c == 4, "word %d is scheduled %d times", p, c)
+	}
+}
+
+func TestSaveAndLoadSchedule(t *testing.T) {
+	assert := assert.New(t)
+
+	fp := filepath.Join(t.TempDir(), "schedule.json")
+	s := wotd.GenerateSchedule(buildWords(366), 12345)
+
+	assert.Nil(wotd.SaveSchedule(s, fp))
+
+	l, e := wotd.LoadSchedule(fp)
+	assert.Nil(e)
+	assert.Equal(s, l)
+}
+
+func TestSelectWordFromSchedule(t *testing.T) {
+	assert := assert.New(t)
+
+	words := buildWords(366)
+	s := wotd.GenerateSchedule(words, 12345)
+	date := time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)
+
+	w, e := wotd.SelectWordFromSchedule(s, words, date)
+	assert.Nil(e)
+	assert.Equal(&words[s.Days[60]], w)
+
+	w, e = wotd.SelectWordFromSchedule(&wotd.Schedule{Days: map[int]int{60: 20}}, buildWords(10), date)
+	assert.Nil(w)
+	assert.NotNil(e)
+	assert.Equal(500, e.Code)
+
+	w, e = wotd.SelectWordFromSchedule(wotd.GenerateSchedule(nil, 1), words, date)
+	assert.Nil(w)
+	assert.NotNil(e)
+}
@@ -0,0 +1,130 @@
+package wotd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	tweetsV2Path     = "/2/tweets"
+	userTweetsV2Path = "/2/users/%s/tweets"
+)
+
+type tweetV2Request struct {
+	Text string `json:"text"`
+}
+
+type tweetV2 struct {
+	ID   string `json:"id"`
+	Text string `json:"text"`
+}
+
+type tweetV2Response struct {
+	Data tweetV2 `json:"data"`
+}
+
+type tweetsV2Response struct {
+	Data []tweetV2 `json:"data"`
+}
+
+// v2ErrorResponse is the problem details body returned by the v2 api on failure
+type v2ErrorResponse struct {
+	Title  string `json:"title"`
+	Detail string `json:"detail"`
+}
+
+// sendTweetV2 creates a tweet using the v2 POST /2/tweets endpoint
+func (tc *TwitterClient) sendTweetV2(ctx context.Context, message string) (*TweetResult, *http.Response, error) {
+	b, err := json.Marshal(tweetV2Request{Text: message})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tc.apiBaseURL+tweetsV2Path, bytes.NewReader(b))
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	var tr tweetV2Response
+	r, err := tc.doV2(req, &tr)
+	if err != nil {
+		log.Printf("error: failed sending tweet, error: %v", err)
+		return nil, r, err
+	}
+
+	return &TweetResult{IDStr: tr.Data.ID, Text: tr.Data.Text, URL: tweetURLPrefix + tr.Data.ID}, r, nil
+}
+
+// HasPostedWordToday checks the user's tweets since midnight UTC for one about the word
+func (tc *TwitterClient) HasPostedWordToday(ctx context.Context, word *Word, userID string) (bool, error) {
+	t, err := tc.findPostedWordToday(ctx, word, userID)
+	if err != nil {
+		return false, err
+	}
+
+	return t != nil, nil
+}
+
+// findPostedWordToday returns the user's tweet about the word since midnight UTC, or nil if there is none
+func (tc *TwitterClient) findPostedWordToday(ctx context.Context, word *Word, userID string) (*TweetResult, error) {
+	q := url.Values{}
+	q.Set("start_time", time.Now().UTC().Truncate(24*time.Hour).Format(time.RFC3339))
+	q.Set("max_results", "20")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tc.apiBaseURL+fmt.Sprintf(userTweetsV2Path, url.PathEscape(userID))+"?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var tr tweetsV2Response
+	if _, err := tc.doV2(req, &tr); err != nil {
+		return nil, err
+	}
+
+	for _, t := range tr.Data {
+		// tweets start with the word, so "hui" does not match an earlier "Rāhui" tweet
+		if strings.HasPrefix(t.Text, word.Word+" : ") {
+			return &TweetResult{IDStr: t.ID, Text: t.Text, URL: tweetURLPrefix + t.ID}, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// doV2 sends an authenticated v2 api request and decodes a successful response body into v
+func (tc *TwitterClient) doV2(req *http.Request, v interface{}) (*http.Response, error) {
+	if tc.bearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+tc.bearerToken)
+	}
+
+	r, err := tc.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return r, err
+	}
+
+	if r.StatusCode < 200 || r.StatusCode > 299 {
+		var er v2ErrorResponse
+		if json.Unmarshal(b, &er) == nil && er.Detail != "" {
+			return r, fmt.Errorf("twitter api: %s: %s: %s", r.Status, er.Title, er.Detail)
+		}
+
+		return r, fmt.Errorf("twitter api: %s", r.Status)
+	}
+
+	return r, json.Unmarshal(b, v)
+}
@@ -0,0 +1,172 @@
+package wotd_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	ent "github.com/wizact/te-reo-bot/pkg/entities"
+	wotd "github.com/wizact/te-reo-bot/pkg/wotd"
+)
+
+func TestSendTweetV2WithBearerToken(t *testing.T) {
+	assert := assert.New(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(http.MethodPost, r.Method)
+		assert.Equal("/2/tweets", r.URL.Path)
+		assert.Equal("Bearer test-token", r.Header.Get("Authorization"))
+		assert.Equal("application/json", r.Header.Get("Content-Type"))
+
+		var body map[string]string
+		assert.Nil(json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal("kia ora : hello", body["text"])
+
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"data":{"id":"1445880548472328192","text":"kia ora : hello"}}`))
+	}))
+	defer srv.Close()
+
+	tc := wotd.NewTwitterClient(&wotd.TwitterCredential{APIV2: true, APIBaseURL: srv.URL, BearerToken: "test-token"})
+
+	tr, r, e := tc.SendTweet(context.Background(), "kia ora : hello")
+
+	assert.Nil(e)
+	assert.Equal(http.StatusCreated, r.StatusCode)
+	assert.Equal("1445880548472328192", tr.IDStr)
+	assert.Equal("kia ora : hello", tr.Text)
+	assert.Equal("https://twitter.com/i/web/status/1445880548472328192", tr.URL)
+}
+
+func TestSendTweetV2WithOAuth1(t *testing.T) {
+	assert := assert.New(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.True(strings.HasPrefix(r.Header.Get("Authorization"), "OAuth "))
+		assert.Contains(r.Header.Get("Authorization"), `oauth_consumer_key="consumer-key"`)
+
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"data":{"id":"1","text":"āe : yes"}}`))
+	}))
+	defer srv.Close()
+
+	tc := wotd.NewTwitterClient(&wotd.TwitterCredential{
+		APIV2:          true,
+		APIBaseURL:     srv.URL,
+		ConsumerKey:    "consumer-key",
+		ConsumerSecret: "consumer-secret",
+		AccessToken:    "access-token",
+		AccessSecret:   "access-secret",
+	})
+
+	tr, _, e := tc.SendTweet(context.Background(), "āe : yes")
+
+	assert.Nil(e)
+	assert.Equal("1", tr.IDStr)
+}
+
+func TestSendTweetV2Error(t *testing.T) {
+	assert := assert.New(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"title":"Forbidden","detail":"You are not permitted to perform this action.","status":403}`))
+	}))
+	defer srv.Close()
+
+	tc := wotd.NewTwitterClient(&wotd.TwitterCredential{APIV2: true, APIBaseURL: srv.URL, BearerToken: "test-token"})
+
+	tr, r, e := tc.SendTweet(context.Background(), "kia ora : hello")
+
+	assert.Nil(tr)
+	assert.Equal(http.StatusForbidden, r.StatusCode)
+	assert.NotNil(e)
+	assert.Contains(e.Error(), "You are not permitted to perform this action.")
+}
+
+func TestHasPostedWordToday(t *testing.T) {
+	assert := assert.New(t)
+
+	midnight := time.Now().UTC().Truncate(24 * time.Hour).Format(time.RFC3339)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(http.MethodGet, r.Method)
+		assert.Equal("/2/users/42/tweets", r.URL.Path)
+		assert.Equal(midnight, r.URL.Query().Get("start_time"))
+		assert.Equal("20", r.URL.Query().Get("max_results"))
+
+		w.Write([]byte(`{"data":[{"id":"7","text":"Korimako : bellbird"}],"meta":{"result_count":1}}`))
+	}))
+	defer srv.Close()
+
+	tc := wotd.NewTwitterClient(&wotd.TwitterCredential{APIV2: true, APIBaseURL: srv.URL, BearerToken: "test-token"})
+
+	posted, e := tc.HasPostedWordToday(context.Background(), &wotd.Word{Word: "Korimako"}, "42")
+	assert.Nil(e)
+	assert.True(posted)
+
+	posted, e = tc.HasPostedWordToday(context.Background(), &wotd.Word{Word: "Kōtare"}, "42")
+	assert.Nil(e)
+	assert.False(posted)
+
+	// "Korimako" ends with "mako" but is a different word
+	posted, e = tc.HasPostedWordToday(context.Background(), &wotd.Word{Word: "mako"}, "42")
+	assert.Nil(e)
+	assert.False(posted)
+}
+
+func TestTweetDetectsDuplicate(t *testing.T) {
+	tests := []struct {
+		name     string
+		timeline string
+		force    bool
+		lookups  int
+		posts    int
+		tweetID  string
+	}{
+		{"existing tweet", `{"data":[{"id":"7","text":"Korimako : bellbird"}],"meta":{"result_count":1}}`, false, 1, 0, "7"},
+		{"empty timeline", `{"meta":{"result_count":0}}`, false, 1, 1, "8"},
+		{"longer word ending in the word", `{"data":[{"id":"7","text":"Te Korimako : the bellbird"}],"meta":{"result_count":1}}`, false, 1, 1, "8"},
+		{"forced repost", `{"data":[{"id":"7","text":"Korimako : bellbird"}],"meta":{"result_count":1}}`, true, 0, 1, "8"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			lookups, posts := 0, 0
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodGet {
+					lookups++
+					w.Write([]byte(tt.timeline))
+					return
+				}
+
+				posts++
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte(`{"data":{"id":"8","text":"Korimako : bellbird"}}`))
+			}))
+			defer srv.Close()
+
+			t.Setenv("TEREOBOT_TWITTER_API_V2", "true")
+			t.Setenv("TEREOBOT_TWITTER_API_BASE_URL", srv.URL)
+			t.Setenv("TEREOBOT_TWITTER_BEARER_TOKEN", "test-token")
+			t.Setenv("TEREOBOT_TWITTER_USER_ID", "42")
+
+			w := httptest.NewRecorder()
+			e := wotd.Tweet(context.Background(), &wotd.Word{Word: "Korimako", Meaning: "bellbird"}, w, tt.force)
+			assert.Nil(e)
+
+			var pr ent.PostResponse
+			assert.Nil(json.NewDecoder(w.Body).Decode(&pr))
+			assert.Equal(tt.tweetID, pr.TwitterId)
+			assert.Equal("https://twitter.com/i/web/status/"+tt.tweetID, pr.TweetURL)
+			assert.Equal(tt.lookups, lookups)
+			assert.Equal(tt.posts, posts)
+		})
+	}
+}
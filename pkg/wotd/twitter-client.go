@@ -1,9 +1,14 @@
 package wotd
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
+	"regexp"
+	"strconv"
+	"time"
 
 	"github.com/dghubble/go-twitter/twitter"
 	"github.com/dghubble/oauth1"
@@ -11,32 +16,139 @@ import (
 	ent "github.com/wizact/te-reo-bot/pkg/entities"
 )
 
+const (
+	tweetURLPrefix = "https://twitter.com/i/web/status/"
+
+	maxTweetLength = 280
+	// tweetURLLength is the length of a t.co link that replaces every url in a tweet
+	tweetURLLength = 23
+)
+
+var tweetURLPattern = regexp.MustCompile(`https?://\S+`)
+
 // TwitterClient is a wrapper for twitter client implementation
 type TwitterClient struct {
-	client *twitter.Client
+	client      *twitter.Client
+	httpClient  *http.Client
+	v2Enabled   bool
+	dryRun      bool
+	apiBaseURL  string
+	bearerToken string
+}
+
+// TweetResult is the tweet created by either the v1.1 or the v2 api
+type TweetResult struct {
+	IDStr string
+	Text  string
+	URL   string
 }
 
 // NewTwitterClient returns an authenticated instance of Twitter client
 func NewTwitterClient(credential *TwitterCredential) *TwitterClient {
-	tc := &TwitterClient{}
-	tc.authenticate(credential)
+	tc := &TwitterClient{
+		v2Enabled:   credential.APIV2,
+		dryRun:      credential.DryRun,
+		apiBaseURL:  credential.APIBaseURL,
+		bearerToken: credential.BearerToken,
+	}
+
+	// a dry run never talks to twitter, so there is no need for an authenticated client
+	if !tc.dryRun {
+		tc.authenticate(credential)
+	}
 
 	return tc
 }
 
-func Tweet(wo *Word, w http.ResponseWriter) *ent.AppError {
+// Tweet posts the word to twitter. Unless force is set, a word already tweeted today is not posted again.
+// Retries stop when ctx is done, so a request that timed out does not post later.
+func Tweet(ctx context.Context, wo *Word, w http.ResponseWriter, force bool) *ent.AppError {
 	var c TwitterCredential
 	envconfig.Process("tereobot", &c)
 	tc := NewTwitterClient(&c)
 
-	t, tr, e := tc.SendTweet(wo.Word + " : " + wo.Meaning)
+	m := wo.Word + " : " + wo.Meaning
+	if ae := ValidateTweetLength(m); ae != nil {
+		return ae
+	}
+
+	if !force && !c.DryRun && c.UserID != "" {
+		et, err := tc.findPostedWordToday(ctx, wo, c.UserID)
+		if err != nil {
+			log.Printf("warn: failed checking for a duplicate tweet, posting anyway, error: %v", err)
+		} else if et != nil {
+			log.Printf("warn: word has already been tweeted today, duplicate_detected: true, existing_tweet_id: %s", et.IDStr)
+			json.NewEncoder(w).Encode(&ent.PostResponse{TwitterId: et.IDStr, TweetURL: et.URL})
+			return nil
+		}
+	}
+
+	t, tr, e := tc.SendTweetWithRetry(ctx, m, DefaultRetryOptions)
+	if !c.DryRun {
+		recordPost("twitter", e == nil)
+	}
 
 	if e == nil {
-		json.NewEncoder(w).Encode(&ent.PostResponse{TwitterId: t.IDStr})
+		json.NewEncoder(w).Encode(&ent.PostResponse{TwitterId: t.IDStr, TweetURL: t.URL})
 		return nil
 	} else {
-		return &ent.AppError{Error: e, Code: tr.StatusCode, Message: "Failed sending the tweet"}
+		code := http.StatusInternalServerError
+		if tr != nil {
+			code = tr.StatusCode
+		}
+
+		return &ent.AppError{Error: e, Code: code, Message: "Failed sending the tweet"}
+	}
+}
+
+// RetryOptions configures how rate limited tweets are retried
+type RetryOptions struct {
+	MaxAttempts  int
+	InitialDelay time.Duration
+	// Cap is the longest exponential backoff wait between two attempts, no cap is applied when it is zero
+	Cap time.Duration
+	// MaxResetWait is the longest wait for the x-rate-limit-reset time, a later reset fails without retrying.
+	// No limit is applied when it is zero.
+	MaxResetWait time.Duration
+}
+
+// DefaultRetryOptions are the retry options used when posting the word of the day.
+// Rate limit windows are 15 minutes, so only a reset that is close enough to finish within the request timeout is waited for.
+var DefaultRetryOptions = RetryOptions{MaxAttempts: 3, InitialDelay: time.Second, Cap: 30 * time.Second, MaxResetWait: 20 * time.Second}
+
+// sleep waits between retries, tests replace it to check the waits without sleeping
+var sleep = sleepContext
+
+// ValidateTweetLength checks the message fits in a tweet using twitter's weighted character count
+func ValidateTweetLength(message string) *ent.AppError {
+	n := tweetLength(message)
+	if n <= maxTweetLength {
+		return nil
+	}
+
+	log.Printf("warn: tweet exceeds the character limit, character_count: %d, limit: %d", n, maxTweetLength)
+
+	return &ent.AppError{Error: fmt.Errorf("tweet has %d characters, the limit is %d", n, maxTweetLength), Code: 400, Message: "Tweet exceeds 280 character limit"}
+}
+
+// tweetLength counts every url as a t.co link, and characters outside latin and common punctuation ranges, e.g. CJK, as two
+func tweetLength(message string) int {
+	n := 0
+	rest := tweetURLPattern.ReplaceAllStringFunc(message, func(string) string {
+		n += tweetURLLength
+		return ""
+	})
+
+	for _, r := range rest {
+		switch {
+		case r <= 0x10FF, r >= 0x2000 && r <= 0x200D, r >= 0x2010 && r <= 0x201F, r >= 0x2032 && r <= 0x2037:
+			n++
+		default:
+			n += 2
+		}
 	}
+
+	return n
 }
 
 // TwitterCredential is a wrapper for consumer and access secrets
@@ -45,20 +157,113 @@ type TwitterCredential struct {
 	ConsumerSecret string
 	AccessToken    string
 	AccessSecret   string
+	// BearerToken is an OAuth 2.0 user context token, used by the v2 api instead of the OAuth 1.0a secrets when set
+	BearerToken string `envconfig:"TWITTER_BEARER_TOKEN"`
+	APIV2       bool   `envconfig:"TWITTER_API_V2"`
+	APIBaseURL  string `envconfig:"TWITTER_API_BASE_URL" default:"https://api.twitter.com"`
+	// DryRun logs tweets instead of sending them, to check the content without using the api quota
+	DryRun bool `envconfig:"TWITTER_DRY_RUN"`
+	// UserID is the id of the bot account, used to look up today's tweets before posting
+	UserID string `envconfig:"TWITTER_USER_ID"`
 }
 
 func (tc *TwitterClient) authenticate(credential *TwitterCredential) {
+	if tc.v2Enabled && tc.bearerToken != "" {
+		// the bearer token is set on each v2 request
+		tc.httpClient = &http.Client{}
+		return
+	}
+
 	config := oauth1.NewConfig(credential.ConsumerKey, credential.ConsumerSecret)
 	token := oauth1.NewToken(credential.AccessToken, credential.AccessSecret)
 	httpClient := config.Client(oauth1.NoContext, token)
 
 	// Twitter client
+	tc.httpClient = httpClient
 	tc.client = twitter.NewClient(httpClient)
 }
 
-// SendTweet updates the authenticated account with a new tweet
-func (tc *TwitterClient) SendTweet(message string) (*twitter.Tweet, *http.Response, error) {
+// SendTweet updates the authenticated account with a new tweet.
+// The v1.1 client does not accept a context, so ctx only cancels v2 requests once they are sent.
+func (tc *TwitterClient) SendTweet(ctx context.Context, message string) (*TweetResult, *http.Response, error) {
+	if tc.dryRun {
+		return tc.sendDryRunTweet(message)
+	}
+
+	if tc.v2Enabled {
+		return tc.sendTweetV2(ctx, message)
+	}
+
 	t, r, e := tc.client.Statuses.Update(message, nil)
-	log.Println(r.Body, r.StatusCode, e.Error())
-	return t, r, e
+	if e != nil {
+		log.Printf("error: failed sending tweet, error: %v", e)
+		return nil, r, e
+	}
+
+	return &TweetResult{IDStr: t.IDStr, Text: t.Text, URL: tweetURLPrefix + t.IDStr}, r, nil
+}
+
+// sendDryRunTweet logs the tweet and returns a synthetic result without calling the api
+func (tc *TwitterClient) sendDryRunTweet(message string) (*TweetResult, *http.Response, error) {
+	log.Printf("info: skipped sending tweet, dry_run: true, message: %q", message)
+
+	id := "dry-run-" + strconv.FormatInt(time.Now().Unix(), 10)
+	r := &http.Response{StatusCode: http.StatusOK, Status: "200 OK", Header: http.Header{}}
+
+	return &TweetResult{IDStr: id, Text: message, URL: tweetURLPrefix + id}, r, nil
+}
+
+// SendTweetWithRetry sends the tweet and retries it while twitter responds with 429 Too Many Requests.
+// It waits until the x-rate-limit-reset time when the header is present, otherwise it backs off exponentially.
+// A reset later than opts.MaxResetWait, or past the ctx deadline, fails straight away with the reset time in the error.
+// Waiting stops with the context error when ctx is done.
+func (tc *TwitterClient) SendTweetWithRetry(ctx context.Context, message string, opts RetryOptions) (*TweetResult, *http.Response, error) {
+	delay := opts.InitialDelay
+
+	for attempt := 1; ; attempt++ {
+		t, r, e := tc.SendTweet(ctx, message)
+		if e == nil || r == nil || r.StatusCode != http.StatusTooManyRequests || attempt >= opts.MaxAttempts {
+			return t, r, e
+		}
+
+		wait := delay
+		reset := r.Header.Get("x-rate-limit-reset")
+		if ts, err := strconv.ParseInt(reset, 10, 64); err == nil {
+			rt := time.Unix(ts, 0)
+			wait = time.Until(rt)
+
+			deadline, hasDeadline := ctx.Deadline()
+			if (opts.MaxResetWait > 0 && wait > opts.MaxResetWait) || (hasDeadline && rt.After(deadline)) {
+				log.Printf("error: tweet is rate limited, not retrying, attempt: %d, rate_limit_reset: %s", attempt, reset)
+				return nil, r, fmt.Errorf("twitter rate limit resets at %s: %w", rt.UTC().Format(time.RFC3339), e)
+			}
+		}
+
+		if wait < 0 {
+			wait = 0
+		}
+
+		log.Printf("warn: tweet is rate limited, retrying, attempt: %d, delay_ms: %d, rate_limit_reset: %s", attempt, wait.Milliseconds(), reset)
+		if err := sleep(ctx, wait); err != nil {
+			return nil, r, err
+		}
+
+		delay *= 2
+		if opts.Cap > 0 && delay > opts.Cap {
+			delay = opts.Cap
+		}
+	}
+}
+
+// sleepContext waits for d, returning early with the context error when ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
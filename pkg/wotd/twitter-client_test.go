@@ -0,0 +1,230 @@
+package wotd_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	ent "github.com/wizact/te-reo-bot/pkg/entities"
+	wotd "github.com/wizact/te-reo-bot/pkg/wotd"
+)
+
+func TestTweetDryRunDoesNotCallTwitter(t *testing.T) {
+	assert := assert.New(t)
+
+	t.Setenv("TEREOBOT_TWITTER_DRY_RUN", "true")
+
+	dt := http.DefaultTransport
+	defer func() { http.DefaultTransport = dt }()
+	http.DefaultTransport = &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			t.Errorf("unexpected connection to %v", addr)
+			return nil, errors.New("dialing is not allowed in a dry run")
+		},
+	}
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	w := httptest.NewRecorder()
+	e := wotd.Tweet(context.Background(), &wotd.Word{Word: "kia ora", Meaning: "hello"}, w, false)
+
+	assert.Nil(e)
+
+	var pr ent.PostResponse
+	assert.Nil(json.NewDecoder(w.Body).Decode(&pr))
+	assert.True(strings.HasPrefix(pr.TwitterId, "dry-run-"))
+	assert.Contains(logs.String(), "info: skipped sending tweet, dry_run: true")
+	assert.Contains(logs.String(), `"kia ora : hello"`)
+}
+
+// recordSleeps replaces the wait between retries for the test, recording the waits instead of sleeping
+func recordSleeps(t *testing.T) *[]time.Duration {
+	var waits []time.Duration
+	t.Cleanup(wotd.SetSleep(func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}))
+
+	return &waits
+}
+
+func TestSendTweetWithRetryWaitsForRateLimitReset(t *testing.T) {
+	assert := assert.New(t)
+	waits := recordSleeps(t)
+
+	reset := time.Now().Add(10 * time.Second)
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls <= 2 {
+			w.Header().Set("x-rate-limit-reset", strconv.FormatInt(reset.Unix(), 10))
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"title":"Too Many Requests","detail":"Too Many Requests","status":429}`))
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"data":{"id":"1","text":"kia ora : hello"}}`))
+	}))
+	defer srv.Close()
+
+	tc := wotd.NewTwitterClient(&wotd.TwitterCredential{APIV2: true, APIBaseURL: srv.URL, BearerToken: "test-token"})
+
+	tr, r, e := tc.SendTweetWithRetry(context.Background(), "kia ora : hello", wotd.RetryOptions{MaxAttempts: 5, InitialDelay: time.Millisecond, Cap: time.Second, MaxResetWait: time.Minute})
+
+	assert.Nil(e)
+	assert.Equal(http.StatusCreated, r.StatusCode)
+	assert.Equal("1", tr.IDStr)
+	assert.Equal(3, calls, "expected exactly two retries")
+
+	// the waits come from the header rather than the 1ms backoff or the 1s cap
+	assert.Len(*waits, 2)
+	for _, w := range *waits {
+		assert.Greater(int64(w), int64(8*time.Second))
+		assert.LessOrEqual(int64(w), int64(10*time.Second))
+	}
+}
+
+func TestSendTweetWithRetryFailsFastOnDistantReset(t *testing.T) {
+	assert := assert.New(t)
+	waits := recordSleeps(t)
+
+	reset := time.Now().Add(15 * time.Minute)
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("x-rate-limit-reset", strconv.FormatInt(reset.Unix(), 10))
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	tc := wotd.NewTwitterClient(&wotd.TwitterCredential{APIV2: true, APIBaseURL: srv.URL, BearerToken: "test-token"})
+
+	tr, r, e := tc.SendTweetWithRetry(context.Background(), "kia ora : hello", wotd.DefaultRetryOptions)
+
+	assert.Nil(tr)
+	assert.Equal(http.StatusTooManyRequests, r.StatusCode)
+	assert.Contains(e.Error(), reset.UTC().Format(time.RFC3339))
+	assert.Equal(1, calls)
+	assert.Empty(*waits)
+}
+
+func TestSendTweetWithRetryBacksOffWithoutResetHeader(t *testing.T) {
+	assert := assert.New(t)
+
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	tc := wotd.NewTwitterClient(&wotd.TwitterCredential{APIV2: true, APIBaseURL: srv.URL, BearerToken: "test-token"})
+
+	tr, r, e := tc.SendTweetWithRetry(context.Background(), "kia ora : hello", wotd.RetryOptions{MaxAttempts: 3, InitialDelay: time.Millisecond, Cap: 5 * time.Millisecond})
+
+	assert.Nil(tr)
+	assert.NotNil(e)
+	assert.Equal(http.StatusTooManyRequests, r.StatusCode)
+	assert.Equal(3, calls)
+}
+
+func TestSendTweetWithRetryDoesNotRetryOtherErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	tc := wotd.NewTwitterClient(&wotd.TwitterCredential{APIV2: true, APIBaseURL: srv.URL, BearerToken: "test-token"})
+
+	_, r, e := tc.SendTweetWithRetry(context.Background(), "kia ora : hello", wotd.RetryOptions{MaxAttempts: 3, InitialDelay: time.Millisecond})
+
+	assert.NotNil(e)
+	assert.Equal(http.StatusUnauthorized, r.StatusCode)
+	assert.Equal(1, calls)
+}
+
+func TestSendTweetWithRetryStopsWhenContextIsDone(t *testing.T) {
+	assert := assert.New(t)
+
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	tc := wotd.NewTwitterClient(&wotd.TwitterCredential{APIV2: true, APIBaseURL: srv.URL, BearerToken: "test-token"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	tr, _, e := tc.SendTweetWithRetry(ctx, "kia ora : hello", wotd.RetryOptions{MaxAttempts: 3, InitialDelay: time.Hour})
+
+	assert.Nil(tr)
+	assert.True(errors.Is(e, context.DeadlineExceeded))
+	assert.Less(int64(time.Since(start)), int64(time.Second))
+	assert.Equal(1, calls, "no attempt should be made after the context is done")
+}
+
+func TestValidateTweetLength(t *testing.T) {
+	assert := assert.New(t)
+
+	url := "https://maoridictionary.co.nz/search?keywords=" + strings.Repeat("a", 60)
+
+	tests := []struct {
+		name    string
+		message string
+		valid   bool
+	}{
+		{"280 characters", strings.Repeat("a", 280), true},
+		{"281 characters", strings.Repeat("a", 281), false},
+		{"url counted as 23 characters", strings.Repeat("a", 256) + " " + url, true},
+		{"url counted as 23 characters over the limit", strings.Repeat("a", 257) + " " + url, false},
+		{"macrons counted as one character", strings.Repeat("ā", 140) + strings.Repeat("ō", 140), true},
+		{"CJK counted as two characters", strings.Repeat("語", 140), true},
+		{"CJK counted as two characters over the limit", strings.Repeat("語", 140) + "a", false},
+	}
+
+	for _, tt := range tests {
+		e := wotd.ValidateTweetLength(tt.message)
+
+		if tt.valid {
+			assert.Nil(e, tt.name)
+		} else if assert.NotNil(e, tt.name) {
+			assert.Equal(400, e.Code, tt.name)
+			assert.Equal("Tweet exceeds 280 character limit", e.Message, tt.name)
+		}
+	}
+}
+
+func TestTweetRejectsLongMessage(t *testing.T) {
+	assert := assert.New(t)
+
+	t.Setenv("TEREOBOT_TWITTER_DRY_RUN", "true")
+
+	w := httptest.NewRecorder()
+	e := wotd.Tweet(context.Background(), &wotd.Word{Word: "kia ora", Meaning: strings.Repeat("hello ", 50)}, w, false)
+
+	assert.NotNil(e)
+	assert.Equal(400, e.Code)
+	assert.Equal(0, w.Body.Len())
+}
@@ -2,33 +2,121 @@ package wotd
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
 	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+	"sync/atomic"
 	"time"
+
+	ent "github.com/wizact/te-reo-bot/pkg/entities"
 )
 
 // WordSelector reads, parses, and selects the word-of-the-day
 type WordSelector struct {
+	cacheTTL time.Duration
+	fsys     fs.FS
+	// fsID keys the cached dictionaries read from fsys, fs.FS values are not always comparable
+	fsID uint64
 }
 
-// SelectWordByDay selects a word from the provided array based on the day of the year
-func (ws *WordSelector) SelectWordByDay(words []Word) *Word {
-	doy := time.Now().YearDay()
-	low := len(words)
-	if doy <= low {
-		return &words[doy-1]
-	} else {
-		return &words[(doy-((doy/low)*low))-1]
+// NewCachingWordSelector returns a word selector that keeps the parsed dictionary in memory for the ttl duration
+func NewCachingWordSelector(ttl time.Duration) *WordSelector {
+	return &WordSelector{cacheTTL: ttl}
+}
+
+// WithFS makes the selector load the dictionary from the provided file system instead of the host file system
+func (ws *WordSelector) WithFS(fsys fs.FS) *WordSelector {
+	ws.fsys = fsys
+	ws.fsID = atomic.AddUint64(&fsIDs, 1)
+	return ws
+}
+
+// SelectWordByDay selects a word from the provided array based on today's day of the year
+func (ws *WordSelector) SelectWordByDay(words []Word) (*Word, *ent.AppError) {
+	return ws.SelectWordByDate(words, time.Now())
+}
+
+// SelectWordByDate selects a word from the provided array based on the day of the year of the date.
+// Days past the end of the dictionary wrap around to its start, see wrapIndex.
+func (ws *WordSelector) SelectWordByDate(words []Word, date time.Time) (*Word, *ent.AppError) {
+	if len(words) == 0 {
+		return nil, &ent.AppError{Error: errors.New("dictionary has no words"), Code: 500, Message: "Failed selecting the word of the day"}
+	}
+
+	w := &words[wrapIndex(date.YearDay(), len(words))]
+	if err := ws.validateSelectedWord(w); err != nil {
+		return nil, err
 	}
+
+	return w, nil
 }
 
-// SelectWordByIndex selects a word from the provided array based on the day of the year
-func (ws *WordSelector) SelectWordByIndex(words []Word, index int) *Word {
-	low := len(words)
-	if index <= low {
-		return &words[index-1]
-	} else {
-		return &words[(index-((index/low)*low))-1]
+// SelectWordByIndex selects a word from the provided array based on the 1-based index.
+// Indexes past the end of the dictionary wrap around to its start, see wrapIndex.
+func (ws *WordSelector) SelectWordByIndex(words []Word, index int) (*Word, *ent.AppError) {
+	if len(words) == 0 {
+		return nil, &ent.AppError{Error: errors.New("dictionary has no words"), Code: 500, Message: "Failed selecting the word of the day"}
+	}
+
+	if index < 1 {
+		return nil, &ent.AppError{Error: fmt.Errorf("word index %d is out of range", index), Code: 400, Message: "Word index must be greater than zero"}
+	}
+
+	w := &words[wrapIndex(index, len(words))]
+	if err := ws.validateSelectedWord(w); err != nil {
+		return nil, err
 	}
+
+	return w, nil
+}
+
+// SelectMultipleWords selects n consecutive words starting from today's word, wrapping around the dictionary
+func (ws *WordSelector) SelectMultipleWords(words []Word, n int) ([]*Word, *ent.AppError) {
+	return ws.SelectMultipleWordsByDate(words, n, time.Now())
+}
+
+// SelectMultipleWordsByDate selects n consecutive words starting from the word of the date, wrapping around the dictionary.
+// n must be between 1 and the dictionary size so the same word is never returned twice,
+// and every selected word must pass the same validation as a single selected word.
+func (ws *WordSelector) SelectMultipleWordsByDate(words []Word, n int, date time.Time) ([]*Word, *ent.AppError) {
+	if n <= 0 || n > len(words) {
+		return nil, &ent.AppError{Error: fmt.Errorf("cannot select %d words from a dictionary of %d", n, len(words)), Code: 400, Message: "Number of words must be between 1 and the dictionary size"}
+	}
+
+	start := date.YearDay()
+	selected := make([]*Word, n)
+	for i := range selected {
+		w := &words[wrapIndex(start+i, len(words))]
+		if err := ws.validateSelectedWord(w); err != nil {
+			return nil, err
+		}
+
+		selected[i] = w
+	}
+
+	return selected, nil
+}
+
+// validateSelectedWord makes sure the selected word has the fields required to compose a post
+func (ws *WordSelector) validateSelectedWord(w *Word) *ent.AppError {
+	if strings.TrimSpace(w.Word) != "" && strings.TrimSpace(w.Meaning) != "" {
+		return nil
+	}
+
+	log.Printf("error: selected word has empty required fields, day_index: %v, operation: validate_selected_word", w.Index)
+
+	return &ent.AppError{Error: fmt.Errorf("word with index %d has an empty word or meaning", w.Index), Code: 500, Message: "Selected word has empty required fields"}
+}
+
+// wrapIndex maps a 1-based position to a 0-based index into a slice of the given size.
+// Positions 1 to size map to 0 to size-1, and larger positions wrap around, so
+// position size+1 maps back to 0. The result is always in [0, size) for position >= 1 and size >= 1.
+func wrapIndex(position, size int) int {
+	return (position - 1) % size
 }
 
 // ParseFile unmarshal a json string to the struct type
@@ -56,6 +144,67 @@ func (ws *WordSelector) ReadFile(filePath string) ([]byte, error) {
 	return f, nil
 }
 
+// ReadFileFromFS reads dictionary json file from the provided file system, e.g. an embed.FS
+func (ws *WordSelector) ReadFileFromFS(fsys fs.FS, path string) ([]byte, error) {
+	f, err := fs.ReadFile(fsys, path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// LoadDictionary reads and parses the dictionary json file, using the in-memory cache when the selector has a cache ttl
+func (ws *WordSelector) LoadDictionary(filePath string) (*Dictionary, error) {
+	if ws.cacheTTL <= 0 {
+		return ws.loadDictionary(filePath)
+	}
+
+	fi, err := ws.stat(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	key := cacheKey{fsID: ws.fsID, path: filePath}
+	if d := cache.get(key, fi.ModTime(), ws.cacheTTL); d != nil {
+		return d, nil
+	}
+
+	d, err := ws.loadDictionary(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	cache.set(key, fi.ModTime(), d)
+
+	return d, nil
+}
+
+func (ws *WordSelector) loadDictionary(filePath string) (*Dictionary, error) {
+	var f []byte
+	var err error
+	if ws.fsys != nil {
+		f, err = ws.ReadFileFromFS(ws.fsys, filePath)
+	} else {
+		f, err = ws.ReadFile(filePath)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ws.ParseFile(f)
+}
+
+func (ws *WordSelector) stat(filePath string) (fs.FileInfo, error) {
+	if ws.fsys != nil {
+		return fs.Stat(ws.fsys, filePath)
+	}
+
+	return os.Stat(filePath)
+}
+
 // Dictionary is the parent element of json file
 type Dictionary struct {
 	Words []Word `json:"dictionary"`
@@ -2,7 +2,11 @@ package wotd_test
 
 import (
 	"bytes"
+	"strconv"
 	"testing"
+	"testing/fstest"
+	"testing/quick"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	wotd "github.com/wizact/te-reo-bot/pkg/wotd"
@@ -38,3 +42,264 @@ func TestReadFile(t *testing.T) {
 	assert.NotNil(f)
 	assert.True(len(f) > 0)
 }
+
+func TestReadFileFromFS(t *testing.T) {
+	assert := assert.New(t)
+
+	ws := wotd.WordSelector{}
+	fsys := fstest.MapFS{
+		"dictionary.json": &fstest.MapFile{Data: []byte(`{"dictionary": [{ "index": 1, "word": "āe", "meaning": "yes" }]}`)},
+	}
+
+	f, e := ws.ReadFileFromFS(fsys, "dictionary.json")
+	assert.Nil(e)
+
+	d, e := ws.ParseFile(f)
+	assert.Nil(e)
+	assert.Equal("āe", d.Words[0].Word)
+
+	_, e = ws.ReadFileFromFS(fsys, "missing.json")
+	assert.NotNil(e)
+}
+
+func TestLoadDictionaryFromFS(t *testing.T) {
+	assert := assert.New(t)
+	wotd.InvalidateDictionaryCache()
+
+	fsys := fstest.MapFS{
+		"dictionary.json": &fstest.MapFile{Data: []byte(`{"dictionary": [{ "index": 1, "word": "aha", "meaning": "what?" }]}`)},
+	}
+
+	for _, ws := range []*wotd.WordSelector{(&wotd.WordSelector{}).WithFS(fsys), wotd.NewCachingWordSelector(time.Minute).WithFS(fsys)} {
+		d, e := ws.LoadDictionary("dictionary.json")
+
+		assert.Nil(e)
+		assert.Equal("aha", d.Words[0].Word)
+	}
+}
+
+func TestSelectWordByDate(t *testing.T) {
+	assert := assert.New(t)
+
+	ws := wotd.WordSelector{}
+	words := buildWords(366)
+
+	tests := []struct {
+		name  string
+		date  time.Time
+		index int
+	}{
+		{"January 1", time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC), 1},
+		{"December 31", time.Date(2023, time.December, 31, 0, 0, 0, 0, time.UTC), 365},
+		{"December 31 in a leap year", time.Date(2024, time.December, 31, 0, 0, 0, 0, time.UTC), 366},
+		{"February 29 in a leap year", time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC), 60},
+		{"March 1 in a leap year", time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC), 61},
+	}
+
+	for _, tt := range tests {
+		w, e := ws.SelectWordByDate(words, tt.date)
+
+		assert.Nil(e, tt.name)
+		assert.Equal(tt.index, w.Index, tt.name)
+	}
+}
+
+func TestSelectWordByDateWithEmptyDictionary(t *testing.T) {
+	assert := assert.New(t)
+
+	ws := wotd.WordSelector{}
+	w, e := ws.SelectWordByDate([]wotd.Word{}, time.Now())
+
+	assert.Nil(w)
+	assert.NotNil(e)
+	assert.Equal(500, e.Code)
+}
+
+func TestSelectWordByIndexWrapsForAllSizes(t *testing.T) {
+	ws := wotd.WordSelector{}
+
+	for size := 1; size <= 500; size++ {
+		words := buildWords(size)
+
+		for index := 1; index <= 500; index++ {
+			w, e := ws.SelectWordByIndex(words, index)
+			if e != nil {
+				t.Fatalf("index %d, size %d: unexpected error %v", index, size, e.Error)
+			}
+
+			if want := ((index - 1) % size) + 1; w.Index != want {
+				t.Fatalf("index %d, size %d: got word %d, want %d", index, size, w.Index, want)
+			}
+		}
+	}
+}
+
+func TestSelectWordByIndexIsInRange(t *testing.T) {
+	ws := wotd.WordSelector{}
+
+	f := func(i, n uint16) bool {
+		index, size := int(i%500)+1, int(n%500)+1
+		words := buildWords(size)
+
+		w, e := ws.SelectWordByIndex(words, index)
+
+		return e == nil && w.Index >= 1 && w.Index <= size
+	}
+
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSelectWordByDateMatchesSelectWordByIndex(t *testing.T) {
+	ws := wotd.WordSelector{}
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	f := func(d, n uint16) bool {
+		date := start.AddDate(0, 0, int(d%366))
+		words := buildWords(int(n%500) + 1)
+
+		byDate, ed := ws.SelectWordByDate(words, date)
+		byIndex, ei := ws.SelectWordByIndex(words, date.YearDay())
+
+		return ed == nil && ei == nil && byDate == byIndex
+	}
+
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSelectWordByDateEdgeCases(t *testing.T) {
+	assert := assert.New(t)
+
+	ws := wotd.WordSelector{}
+	leapDay := time.Date(2024, time.December, 31, 0, 0, 0, 0, time.UTC)
+
+	w, e := ws.SelectWordByDate(buildWords(365), leapDay)
+	assert.Nil(e)
+	assert.Equal(1, w.Index, "day 366 wraps to the first word of a 365 word dictionary")
+
+	w, e = ws.SelectWordByDate(buildWords(366), leapDay)
+	assert.Nil(e)
+	assert.Equal(366, w.Index, "day 366 selects the last word of a 366 word dictionary")
+
+	w, e = ws.SelectWordByDate(buildWords(1), leapDay)
+	assert.Nil(e)
+	assert.Equal(1, w.Index, "a single word dictionary always selects its only word")
+}
+
+func TestSelectWordByIndexRejectsNonPositiveIndex(t *testing.T) {
+	assert := assert.New(t)
+
+	ws := wotd.WordSelector{}
+
+	for _, index := range []int{0, -1} {
+		w, e := ws.SelectWordByIndex(buildWords(10), index)
+
+		assert.Nil(w)
+		assert.NotNil(e)
+		assert.Equal(400, e.Code)
+	}
+}
+
+func TestSelectMultipleWordsSingleWordMatchesSelectWordByDay(t *testing.T) {
+	assert := assert.New(t)
+
+	ws := wotd.WordSelector{}
+	words := buildWords(366)
+
+	m, e := ws.SelectMultipleWords(words, 1)
+	assert.Nil(e)
+
+	w, e := ws.SelectWordByDay(words)
+	assert.Nil(e)
+
+	assert.Len(m, 1)
+	assert.Equal(w, m[0])
+}
+
+func TestSelectMultipleWordsWrapsAround(t *testing.T) {
+	assert := assert.New(t)
+
+	ws := wotd.WordSelector{}
+	words := buildWords(365)
+	day360 := time.Date(2023, time.December, 26, 0, 0, 0, 0, time.UTC)
+
+	m, e := ws.SelectMultipleWordsByDate(words, 10, day360)
+	assert.Nil(e)
+
+	got := []int{}
+	for _, w := range m {
+		got = append(got, w.Index)
+	}
+	assert.Equal([]int{360, 361, 362, 363, 364, 365, 1, 2, 3, 4}, got)
+}
+
+func TestSelectMultipleWordsRejectsInvalidCount(t *testing.T) {
+	assert := assert.New(t)
+
+	ws := wotd.WordSelector{}
+	words := buildWords(5)
+
+	for _, n := range []int{0, -1, 6} {
+		m, e := ws.SelectMultipleWords(words, n)
+
+		assert.Nil(m)
+		assert.NotNil(e)
+		assert.Equal(400, e.Code)
+	}
+}
+
+func TestSelectWordRejectsWordWithEmptyFields(t *testing.T) {
+	assert := assert.New(t)
+
+	ws := wotd.WordSelector{}
+	words := buildWords(366)
+	words[59].Meaning = ""
+	words[60].Word = "  "
+	leapDay := time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)
+
+	w, e := ws.SelectWordByDate(words, leapDay)
+	assert.Nil(w)
+	assert.NotNil(e)
+	assert.Equal(500, e.Code)
+	assert.Equal("Selected word has empty required fields", e.Message)
+
+	w, e = ws.SelectWordByIndex(words, 61)
+	assert.Nil(w)
+	assert.NotNil(e)
+	assert.Equal(500, e.Code)
+
+	w, e = ws.SelectWordByIndex(words, 62)
+	assert.Nil(e)
+	assert.Equal(62, w.Index)
+}
+
+func TestSelectMultipleWordsRejectsWordWithEmptyFields(t *testing.T) {
+	assert := assert.New(t)
+
+	ws := wotd.WordSelector{}
+	words := buildWords(366)
+	words[61].Meaning = ""
+	leapDay := time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)
+
+	m, e := ws.SelectMultipleWordsByDate(words, 3, leapDay)
+	assert.Nil(m)
+	assert.NotNil(e)
+	assert.Equal(500, e.Code)
+	assert.Equal("Selected word has empty required fields", e.Message)
+
+	m, e = ws.SelectMultipleWordsByDate(words, 2, leapDay)
+	assert.Nil(e)
+	assert.Len(m, 2)
+}
+
+func buildWords(n int) []wotd.Word {
+	words := make([]wotd.Word, n)
+	for i := range words {
+		words[i] = wotd.Word{Index: i + 1, Word: "kupu " + strconv.Itoa(i+1), Meaning: "word " + strconv.Itoa(i+1)}
+	}
+
+	return words
+}